@@ -0,0 +1,289 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+// IAudit Приёмник событий аудита
+type IAudit interface {
+	Audit(event *AuditEvent) error
+}
+
+// AuditEvent Событие аудита обработчика маршрута
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Action Read, Created, Updated, Deleted или Failed, если обработчик вернул ошибку
+	Action    string             `json:"action"`
+	Method    string             `json:"method"`
+	Path      string             `json:"path"`
+	Identity  *security.Identity `json:"identity,omitempty"`
+	ModelName string             `json:"model_name"`
+	Query     string             `json:"query,omitempty"`
+	Params    *QueryParams       `json:"-"`
+	Filter    *Filter            `json:"filter,omitempty"`
+	Body      any                `json:"body,omitempty"`
+	Changes   []RecordChange     `json:"changes,omitempty"`
+	Status    int                `json:"status"`
+	Error     string             `json:"error,omitempty"`
+	Duration  time.Duration      `json:"duration"`
+}
+
+var ErrAuditQueueFull = errors.New("audit queue is full")
+
+// SetIAudit Установка приёмника событий аудита
+func (r *CRUD) SetIAudit(audit IAudit) *CRUD {
+	r.IAudit = audit
+	return r
+}
+
+// auditor Сбор события аудита в обработчике маршрута. Send запоминает статус и ошибку ответа
+type auditor struct {
+	r         *CRUD
+	mu        sync.Mutex
+	event     AuditEvent
+	start     time.Time
+	streaming bool
+}
+
+// audit Начало события аудита
+func (r *CRUD) audit(c *ewa.Context, action string) *auditor {
+	a := &auditor{r: r, start: time.Now()}
+	if r.IAudit == nil {
+		return a
+	}
+	a.event = AuditEvent{
+		Time:      a.start,
+		Action:    action,
+		Method:    c.Method(),
+		Path:      c.Path(),
+		Identity:  c.Identity,
+		ModelName: r.ModelName,
+		Query:     c.QueryValues().Encode(),
+	}
+	return a
+}
+
+// params Параметры запроса и тело события
+func (a *auditor) params(params *QueryParams, body *Body) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.event.Params = params
+	if params != nil {
+		a.event.Filter = params.Filter
+	}
+	if body != nil {
+		if body.IsArray {
+			records := make([]map[string]interface{}, len(body.Array))
+			for i, record := range body.Array {
+				records[i] = a.r.withoutExcludes(record)
+			}
+			a.event.Body = records
+		} else {
+			a.event.Body = a.r.withoutExcludes(body.Data)
+		}
+	}
+}
+
+// withoutExcludes Копия записи без исключённых полей, сама запись пишется в бд и не изменяется
+func (r *CRUD) withoutExcludes(record map[string]interface{}) map[string]interface{} {
+	if record == nil {
+		return nil
+	}
+	m := make(map[string]interface{}, len(record))
+	for key, value := range record {
+		if !isExcluded(key, r.Excludes) {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+// changes Изменения записей по полям
+func (a *auditor) changes(changes []RecordChange) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.event.Changes = changes
+}
+
+// result Статус и ошибка ответа
+func (a *auditor) result(status int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.event.Status = status
+	a.event.Error = ""
+	if err != nil {
+		a.event.Status = ProblemOf(err, status).Status
+		a.event.Error = err.Error()
+	}
+}
+
+func (a *auditor) Send(c *ewa.Context, state string, status int, data any) error {
+	err, _ := data.(error)
+	a.result(status, err)
+	return a.r.Send(c, state, status, data)
+}
+
+// notModified Ответ 304 без тела
+func (a *auditor) notModified(c *ewa.Context) error {
+	a.result(consts.StatusNotModified, nil)
+	return c.SendStatus(consts.StatusNotModified)
+}
+
+// stream Построчная выдача, событие отправляется по её завершении со статусом и ошибкой посреди потока,
+// так как поток пишется уже после возврата из обработчика маршрута
+func (a *auditor) stream(records RecordsFunc) RecordsFunc {
+	a.mu.Lock()
+	a.streaming = true
+	a.mu.Unlock()
+	return func(ctx context.Context, yield func(record Map) error) (int, error) {
+		status, err := records(ctx, yield)
+		a.result(status, err)
+		a.send()
+		return status, err
+	}
+}
+
+// done Отправка события в приёмник аудита, если её не отложила построчная выдача
+func (a *auditor) done() {
+	a.mu.Lock()
+	streaming := a.streaming
+	a.mu.Unlock()
+	if !streaming {
+		a.send()
+	}
+}
+
+// send Отправка события в приёмник аудита
+func (a *auditor) send() {
+	if a.r.IAudit == nil {
+		return
+	}
+	a.mu.Lock()
+	event := a.event
+	a.mu.Unlock()
+	event.Duration = time.Since(a.start)
+	if len(event.Error) > 0 {
+		event.Action = Failed
+	}
+	_ = a.r.IAudit.Audit(&event)
+}
+
+// WriterAudit Запись событий аудита в io.Writer в формате JSON lines
+type WriterAudit struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterAudit Инициализация записи событий в w
+func NewWriterAudit(w io.Writer) *WriterAudit {
+	return &WriterAudit{w: w}
+}
+
+func (a *WriterAudit) Audit(event *AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.w.Write(append(b, '\n'))
+	return err
+}
+
+// FileAudit Запись событий аудита в файл в формате JSON lines
+type FileAudit struct {
+	*WriterAudit
+	f *os.File
+}
+
+// NewFileAudit Открытие файла аудита на дозапись
+func NewFileAudit(name string) (*FileAudit, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAudit{WriterAudit: NewWriterAudit(f), f: f}, nil
+}
+
+// Close Закрытие файла аудита
+func (a *FileAudit) Close() error {
+	return a.f.Close()
+}
+
+// AsyncAudit Асинхронная доставка событий аудита через буфер.
+// Запросы не ждут приёмник: при заполненном буфере событие отбрасывается
+type AsyncAudit struct {
+	// OnError Обработчик ошибок приёмника, устанавливается до первого события
+	OnError func(err error)
+
+	sink    IAudit
+	mu      sync.RWMutex
+	closed  bool
+	events  chan *AuditEvent
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// NewAsyncAudit Инициализация асинхронной доставки в sink с буфером на size событий
+func NewAsyncAudit(sink IAudit, size int) *AsyncAudit {
+	a := &AsyncAudit{
+		sink:   sink,
+		events: make(chan *AuditEvent, size),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *AsyncAudit) run() {
+	defer close(a.done)
+	for event := range a.events {
+		if err := a.sink.Audit(event); err != nil && a.OnError != nil {
+			a.OnError(err)
+		}
+	}
+}
+
+func (a *AsyncAudit) Audit(event *AuditEvent) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return ErrAuditQueueFull
+	}
+	select {
+	case a.events <- event:
+		return nil
+	default:
+		a.dropped.Add(1)
+		return ErrAuditQueueFull
+	}
+}
+
+// Dropped Количество отброшенных событий
+func (a *AsyncAudit) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Close Доставка оставшихся событий и остановка
+func (a *AsyncAudit) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.mu.Unlock()
+	<-a.done
+	return nil
+}
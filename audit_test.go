@@ -0,0 +1,151 @@
+package crud
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+// memoryAudit Приёмник событий аудита в памяти
+type memoryAudit struct {
+	mu     sync.Mutex
+	events []*AuditEvent
+	wait   chan struct{}
+}
+
+func (m *memoryAudit) Audit(event *AuditEvent) error {
+	if m.wait != nil {
+		<-m.wait
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func TestAudit_Handlers(t *testing.T) {
+	audit := new(memoryAudit)
+	r := New(h).SetModelName("users").SetFieldIdName("id").SetExcludes("password").SetIAudit(audit)
+
+	tc := newTestContext().
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"name":"Name","password":"secret"}`)
+	ctx := &ewa.Context{IContext: tc, Identity: &security.Identity{Username: "admin"}}
+	if err := r.CreateHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	tc = newTestContext().AddQuery(filterParamName, `{"orders":["age desc"]}`)
+	ctx = &ewa.Context{IContext: tc}
+	if err := r.ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	tc = newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON)
+	ctx = &ewa.Context{IContext: tc}
+	if err := r.ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEq(t, len(audit.events), 3)
+	e := audit.events[0]
+	assertEq(t, e.Action, Created)
+	assertEq(t, e.Identity.Username, "admin")
+	assertEq(t, e.ModelName, "users")
+	assertEq(t, e.Status, 200)
+	assertEq(t, e.Body.(map[string]interface{})["name"], "Name")
+	_, ok := e.Body.(map[string]interface{})["password"]
+	assertEq(t, ok, false)
+
+	e = audit.events[1]
+	assertEq(t, e.Action, Failed)
+	assertEq(t, e.Status, 400)
+	assertEq(t, e.Query, "~=%7B%22orders%22%3A%5B%22age+desc%22%5D%7D")
+	assertEq(t, e.Filter.Orders[0], "age desc")
+	assertEq(t, len(e.Error) > 0, true)
+
+	assertEq(t, audit.events[2].Action, Read)
+	assertEq(t, audit.events[2].Status, 200)
+}
+
+func TestAudit_StreamStopped(t *testing.T) {
+	audit := new(memoryAudit)
+	h := &endlessHandlers{done: make(chan error, 1)}
+	tc := newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON)
+	r := New(h).SetModelName("table").SetIAudit(audit)
+	if err := r.ReadHandler(&ewa.Context{IContext: failedSendContext{tc}}, nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	// Событие отправляется по завершении потока, а не по возврату из обработчика
+	deadline := time.Now().Add(time.Second)
+	for {
+		audit.mu.Lock()
+		n := len(audit.events)
+		audit.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("audit event is not sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assertEq(t, len(audit.events), 1)
+	assertEq(t, audit.events[0].Action, Failed)
+	assertEq(t, audit.events[0].Status, 500)
+}
+
+func TestWriterAudit(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewWriterAudit(&buf)
+	if err := a.Audit(&AuditEvent{Action: Deleted, ModelName: "users", Status: 200}); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, buf.String(), `{"time":"0001-01-01T00:00:00Z","action":"DELETED","method":"","path":"","model_name":"users","status":200,"duration":0}`+"\n")
+
+	name := filepath.Join(t.TempDir(), "audit.log")
+	f, err := NewFileAudit(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = f.Audit(&AuditEvent{Action: Read}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, strings.Count(string(b), "\n"), 2)
+}
+
+func TestAsyncAudit(t *testing.T) {
+	sink := &memoryAudit{wait: make(chan struct{})}
+	a := NewAsyncAudit(sink, 1)
+
+	// Первое событие ждёт приёмник, второе в буфере, третье отбрасывается
+	assertEq(t, a.Audit(&AuditEvent{Action: Read}), nil)
+	for a.Audit(&AuditEvent{Action: Created}) != nil {
+	}
+	assertEq(t, errors.Is(a.Audit(&AuditEvent{Action: Updated}), ErrAuditQueueFull), true)
+	assertEq(t, a.Dropped() > 0, true)
+
+	close(sink.wait)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, len(sink.events), 2)
+	assertEq(t, sink.events[1].Action, Created)
+	assertEq(t, errors.Is(a.Audit(&AuditEvent{}), ErrAuditQueueFull), true)
+}
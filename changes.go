@@ -0,0 +1,113 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/ewa-go/ewa"
+)
+
+// FieldChange Изменение значения поля
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// RecordChange Изменения полей записи
+type RecordChange struct {
+	ID     any           `json:"id,omitempty"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// SetTrackChanges Получение затрагиваемых записей до изменения и удаления для расчёта изменений по полям.
+// Изменения передаются обработчикам после обращения в бд в QueryParams.Changes и в событие аудита
+func (r *CRUD) SetTrackChanges(track bool) *CRUD {
+	r.TrackChanges = track
+	return r
+}
+
+// Changes Изменения записей, затрагиваемых запросом. Если тело не передано, то записи удаляются.
+// Значения полей из Excludes не раскрываются
+func (r *CRUD) Changes(c *ewa.Context, params *QueryParams, body *Body) (int, []RecordChange, error) {
+	status, records, err := r.affected(c, params)
+	if err != nil {
+		return status, nil, err
+	}
+	changes := make([]RecordChange, 0, len(records))
+	for _, record := range records {
+		change := RecordChange{ID: record[r.FieldIdName]}
+		switch {
+		case body == nil:
+			for _, key := range sortedKeys(record) {
+				change.Fields = append(change.Fields, r.fieldChange(key, record[key], nil))
+			}
+		default:
+			data := r.bodyOf(body, change.ID)
+			for _, key := range sortedKeys(data) {
+				if old := record[key]; !equalValues(old, data[key]) {
+					change.Fields = append(change.Fields, r.fieldChange(key, old, data[key]))
+				}
+			}
+		}
+		if len(change.Fields) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return status, changes, nil
+}
+
+// affected Записи, затрагиваемые запросом: по идентификатору или по условиям без подсчёта общего количества
+func (r *CRUD) affected(c *ewa.Context, params *QueryParams) (int, Maps, error) {
+	if params != nil && params.ID != nil {
+		status, record, err := r.GetRecord(c, r, params)
+		if err != nil || record == nil {
+			return status, nil, err
+		}
+		return status, Maps{record}, nil
+	}
+	var p QueryParams
+	if params != nil {
+		p = *params
+	}
+	p.Count = CountNone
+	status, records, _, err := r.GetRecords(c, r, &p)
+	return status, records, err
+}
+
+// bodyOf Новые значения записи: тело запроса или элемент массива с тем же идентификатором
+func (r *CRUD) bodyOf(body *Body, id any) map[string]interface{} {
+	if !body.IsArray {
+		return body.ToMap()
+	}
+	for i := range body.Array {
+		if data := body.ToArrayMap(i); data != nil && equalValues(data[r.FieldIdName], id) {
+			return data
+		}
+	}
+	return nil
+}
+
+func (r *CRUD) fieldChange(field string, old, new any) FieldChange {
+	if isExcluded(field, r.Excludes) {
+		return FieldChange{Field: field}
+	}
+	return FieldChange{Field: field, Old: old, New: new}
+}
+
+// equalValues Сравнение значений из бд и тела запроса, например int64(1) и float64(1)
+func equalValues(a, b any) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,54 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+func TestEqualValues(t *testing.T) {
+	assertEq(t, equalValues(int64(1), float64(1)), true)
+	assertEq(t, equalValues("a", "a"), true)
+	assertEq(t, equalValues(nil, "a"), false)
+	assertEq(t, equalValues([]any{"a"}, []string{"a"}), true)
+}
+
+func TestUpdateHandler_TrackChanges(t *testing.T) {
+	d, r := newFakeSQL()
+	audit := new(memoryAudit)
+	r.SetTrackChanges(true).SetIAudit(audit)
+
+	var changes []RecordChange
+	after := func(c *ewa.Context, r *CRUD, i *security.Identity, q *QueryParams, result any) (int, error) {
+		changes = q.Changes
+		return consts.StatusOK, nil
+	}
+	tc := newTestContext().
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		AddQuery("name[%]", "Name%").
+		SetBody(`{"name":"Name2"}`)
+	if err := r.UpdateHandler(&ewa.Context{IContext: tc}, nil, after); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, d.queries[0], `SELECT * FROM "public"."users" WHERE "name"::text like $1`)
+	assertEq(t, len(changes), 1)
+	assertEq(t, changes[0].ID, int64(1))
+	assertEq(t, changes[0].Fields[0], FieldChange{Field: "name", Old: "Name1", New: "Name2"})
+	assertEq(t, len(audit.events[0].Changes), 1)
+
+	// При удалении изменения содержат все поля записи, значения исключённых полей скрыты
+	r.SetExcludes("name")
+	tc = newTestContext().SetParam("id", "1")
+	if err := r.DeleteHandler(&ewa.Context{IContext: tc}, nil, after); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, len(changes), 1)
+	assertEq(t, changes[0].Fields[0], FieldChange{Field: "id", Old: int64(1)})
+	assertEq(t, changes[0].Fields[1], FieldChange{Field: "name"})
+	assertEq(t, audit.events[1].Action, Deleted)
+	assertEq(t, len(audit.events[1].Changes), 1)
+}
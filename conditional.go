@@ -0,0 +1,98 @@
+package crud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+// SetModifiedColumn Установка столбца времени изменения записей. Наибольшее время среди выбираемых записей становится Last-Modified ответа
+func (r *CRUD) SetModifiedColumn(column string) *CRUD {
+	r.ModifiedColumn = column
+	return r
+}
+
+// notModifiedSince Установка Last-Modified по времени изменения записей от обработчика.
+// Возвращает true, если записи не изменились с If-Modified-Since и выборку можно не выполнять.
+// If-Modified-Since не учитывается при заголовке If-None-Match
+func (r *CRUD) notModifiedSince(c *ewa.Context, params *QueryParams) (int, bool, error) {
+	h, ok := r.IHandlers.(ILastModifiedHandlers)
+	if !ok {
+		return consts.StatusOK, false, nil
+	}
+	status, modified, err := h.LastModified(c, r, params)
+	if err != nil || modified.IsZero() {
+		return status, false, err
+	}
+	modified = modified.UTC().Truncate(time.Second)
+	c.Set(consts.HeaderLastModified, modified.Format(http.TimeFormat))
+	if len(c.Get(consts.HeaderIfNoneMatch)) > 0 {
+		return consts.StatusOK, false, nil
+	}
+	since, err := http.ParseTime(c.Get(consts.HeaderIfModifiedSince))
+	return consts.StatusOK, err == nil && !modified.After(since), nil
+}
+
+// sendRead Отправка результата чтения с ETag: тегом записи etag, иначе хешем закодированного ответа и общего количества записей total.
+// Хеш ответа считается только с IEncodeResponse. При совпадении с If-None-Match отправляется 304 без тела
+func (r *CRUD) sendRead(c *ewa.Context, a *auditor, status int, data any, etag, total string) error {
+	if status != consts.StatusOK {
+		return a.Send(c, Read, status, data)
+	}
+	e, ok := r.IResponse.(IEncodeResponse)
+	if !ok {
+		if len(etag) > 0 && r.setETag(c, etag) {
+			return a.notModified(c)
+		}
+		return a.Send(c, Read, status, data)
+	}
+	contentType, content, err := e.Encode(c, Read, data)
+	if err != nil {
+		// Ошибку кодирования отправит IResponse
+		return a.Send(c, Read, status, data)
+	}
+	if len(etag) == 0 {
+		etag = contentETag(contentType, total, content)
+	}
+	if r.setETag(c, etag) {
+		return a.notModified(c)
+	}
+	a.result(status, nil)
+	return c.Send(status, contentType, content)
+}
+
+// setETag Установка ETag и Vary: Accept, так как формат ответа выбирается по Accept.
+// Возвращает true при совпадении с If-None-Match
+func (r *CRUD) setETag(c *ewa.Context, etag string) bool {
+	c.Set(consts.HeaderETag, etag)
+	c.Set(consts.HeaderVary, consts.HeaderAccept)
+	return noneMatch(c.Get(consts.HeaderIfNoneMatch), etag)
+}
+
+// contentETag Строгий тег по типу содержимого, общему количеству записей и телу ответа
+func contentETag(contentType, total string, content []byte) string {
+	h := sha256.New()
+	h.Write([]byte(contentType + "\n" + total + "\n"))
+	h.Write(content)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// noneMatch Слабое сравнение тегов из If-None-Match
+func noneMatch(header, etag string) bool {
+	if len(header) == 0 || len(etag) == 0 {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+func TestNoneMatch(t *testing.T) {
+	assertEq(t, noneMatch(`"1", W/"2"`, `"2"`), true)
+	assertEq(t, noneMatch(`*`, `"2"`), true)
+	assertEq(t, noneMatch(`"1"`, `"2"`), false)
+	assertEq(t, noneMatch(``, `"2"`), false)
+}
+
+func TestReadHandler_IfNoneMatch(t *testing.T) {
+	_, r := newFakeSQL()
+
+	read := func(tc *testContext) *testContext {
+		if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		return tc
+	}
+	tc := read(newTestContext())
+	assertEq(t, tc.response.status, 200)
+	etag := tc.response.headers[consts.HeaderETag]
+	assertEq(t, len(etag), 34)
+	assertEq(t, tc.response.headers[HeaderTotal], "2")
+
+	tc = read(newTestContext().SetHeader(consts.HeaderIfNoneMatch, etag))
+	assertEq(t, tc.response.status, 304)
+	assertEq(t, len(tc.response.body), 0)
+
+	// Тег зависит от типа содержимого и общего количества записей
+	tc = read(newTestContext().SetHeader(consts.HeaderAccept, consts.MIMEApplicationXML))
+	assertEq(t, tc.response.headers[consts.HeaderETag] != etag, true)
+	tc = read(newTestContext().SetHeader(HeaderPrefer, "count=none").SetHeader(consts.HeaderIfNoneMatch, etag))
+	assertEq(t, tc.response.status, 200)
+
+	// Запись по идентификатору получает тег записи для If-Match
+	tc = read(newTestContext().SetParam("id", "1"))
+	etag = tc.response.headers[consts.HeaderETag]
+	assertEq(t, etag, r.ETag(Map{"id": int64(1), "name": "Name1"}))
+	assertEq(t, tc.response.headers[consts.HeaderVary], consts.HeaderAccept)
+	tc = read(newTestContext().SetParam("id", "1").SetHeader(consts.HeaderIfNoneMatch, strings.TrimPrefix(etag, "W/")))
+	assertEq(t, tc.response.status, 304)
+
+	// Без IEncodeResponse тег записи тоже отправляется
+	r.IResponse = struct{ IResponse }{r.IResponse}
+	tc = read(newTestContext().SetParam("id", "1"))
+	assertEq(t, tc.response.headers[consts.HeaderETag], etag)
+	tc = read(newTestContext().SetParam("id", "1").SetHeader(consts.HeaderIfNoneMatch, etag))
+	assertEq(t, tc.response.status, 304)
+}
+
+func TestReadHandler_IfModifiedSince(t *testing.T) {
+	d, r := newFakeSQL()
+	r.SetModifiedColumn("updated_at")
+	modified := time.Date(2024, 5, 1, 10, 0, 0, 500, time.UTC)
+	rows := d.rows
+	d.rows = func(query string) ([]string, [][]driver.Value) {
+		if strings.HasPrefix(query, "SELECT max(") {
+			return []string{"max"}, [][]driver.Value{{modified}}
+		}
+		return rows(query)
+	}
+
+	tc := newTestContext().AddQuery("name", "Name1").SetHeader(consts.HeaderIfModifiedSince, modified.Format(http.TimeFormat))
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 304)
+	assertEq(t, tc.response.headers[consts.HeaderLastModified], "Wed, 01 May 2024 10:00:00 GMT")
+	assertEq(t, len(d.queries), 1)
+	assertEq(t, d.queries[0], `SELECT max("updated_at") FROM "public"."users" WHERE "name" = $1`)
+
+	tc = newTestContext().SetHeader(consts.HeaderIfModifiedSince, modified.Add(-time.Hour).Format(http.TimeFormat))
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+
+	// If-Modified-Since не учитывается при If-None-Match
+	tc = newTestContext().
+		SetHeader(consts.HeaderIfModifiedSince, modified.Format(http.TimeFormat)).
+		SetHeader(consts.HeaderIfNoneMatch, `"0"`)
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+}
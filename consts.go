@@ -1,11 +1,12 @@
 package crud
 
 const (
-	Read    = "READ"
-	Created = "CREATED"
-	Updated = "UPDATED"
-	Deleted = "DELETED"
-	Failed  = "FAILED"
+	Read     = "READ"
+	Created  = "CREATED"
+	Updated  = "UPDATED"
+	Deleted  = "DELETED"
+	Restored = "RESTORED"
+	Failed   = "FAILED"
 )
 
 const (
@@ -15,8 +16,13 @@ const (
 )
 
 const (
-	HeaderXContentType = "X-Content-Type"
-	HeaderTableInfo    = "Table-Info"
-	HeaderTableType    = "Table-Type"
-	HeaderTotal        = "Total"
+	HeaderXContentType      = "X-Content-Type"
+	HeaderTableInfo         = "Table-Info"
+	HeaderTableType         = "Table-Type"
+	HeaderTotal             = "Total"
+	HeaderNextCursor        = "Next-Cursor"
+	HeaderPrevCursor        = "Prev-Cursor"
+	HeaderPrefer            = "Prefer"
+	HeaderPreferenceApplied = "Preference-Applied"
+	HeaderWithDeleted       = "With-Deleted"
 )
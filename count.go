@@ -0,0 +1,33 @@
+package crud
+
+import (
+	"strings"
+)
+
+// Count Способ подсчёта общего количества записей для заголовка Total
+type Count string
+
+const (
+	// CountExact Точный подсчёт count(*)
+	CountExact Count = "exact"
+	// CountEstimated Оценка по статистике планировщика
+	CountEstimated Count = "estimated"
+	// CountNone Без подсчёта, заголовок Total не возвращается
+	CountNone Count = "none"
+)
+
+// ParseCount Способ подсчёта из заголовка Prefer: count=exact|estimated|none.
+// Если способ не указан или не известен, то возвращается пустое значение - точный подсчёт по-умолчанию
+func ParseCount(prefer string) Count {
+	for _, pref := range strings.FieldsFunc(prefer, func(r rune) bool { return r == ',' || r == ';' }) {
+		a := strings.SplitN(strings.TrimSpace(pref), "=", 2)
+		if len(a) != 2 || strings.ToLower(strings.TrimSpace(a[0])) != "count" {
+			continue
+		}
+		switch count := Count(strings.ToLower(strings.Trim(strings.TrimSpace(a[1]), `"`))); count {
+		case CountExact, CountEstimated, CountNone:
+			return count
+		}
+	}
+	return ""
+}
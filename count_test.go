@@ -0,0 +1,44 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/ewa-go/ewa"
+)
+
+func TestParseCount(t *testing.T) {
+	assertEq(t, ParseCount("count=exact"), CountExact)
+	assertEq(t, ParseCount("return=minimal, count=estimated"), CountEstimated)
+	assertEq(t, ParseCount(`Count="none"`), CountNone)
+	assertEq(t, ParseCount("count=planned"), Count(""))
+	assertEq(t, ParseCount(""), Count(""))
+}
+
+func TestReadHandler_Count(t *testing.T) {
+	tc := newTestContext().SetHeader(HeaderPrefer, "count=none")
+	ctx := &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, ok := tc.response.headers[HeaderTotal]
+	assertEq(t, ok, false)
+	assertEq(t, tc.response.headers[HeaderPreferenceApplied], "count=none")
+
+	tc = newTestContext()
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.headers[HeaderTotal], "2")
+	_, ok = tc.response.headers[HeaderPreferenceApplied]
+	assertEq(t, ok, false)
+
+	// Обработчик не сообщил о подсчёте
+	tc = newTestContext().SetHeader(HeaderPrefer, "count=estimated")
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, ok = tc.response.headers[HeaderPreferenceApplied]
+	assertEq(t, ok, false)
+}
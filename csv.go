@@ -0,0 +1,123 @@
+package crud
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+const MIMETextCSVCharsetUTF8 = "text/csv; charset=utf-8"
+
+// CSV Настройки выгрузки записей в text/csv
+type CSV struct {
+	// Comma Разделитель полей, по-умолчанию запятая
+	Comma rune
+	// BOM Добавлять метку порядка байтов UTF-8, нужна для открытия выгрузки в Excel
+	BOM bool
+}
+
+var fileNameRegexp = regexp.MustCompile(`[^\w.-]+`)
+
+// SetCSV Установка разделителя и BOM для выгрузки в text/csv
+func (r *CRUD) SetCSV(comma rune, bom bool) *CRUD {
+	r.CSV = &CSV{Comma: comma, BOM: bom}
+	return r
+}
+
+// SendCSV Потоковая выгрузка записей в text/csv построчно.
+// Заголовок строится по Filter.Fields или Columns без Excludes, имя файла - по ModelName
+func (r *CRUD) SendCSV(c *ewa.Context, params *QueryParams, records RecordsFunc) error {
+	opts := CSV{Comma: ','}
+	if r.CSV != nil {
+		opts = *r.CSV
+		if opts.Comma == 0 {
+			opts.Comma = ','
+		}
+	}
+	name := fileNameRegexp.ReplaceAllString(r.ModelName, "_")
+	if len(name) == 0 {
+		name = "export"
+	}
+	headers := map[string]string{
+		consts.HeaderContentDisposition: fmt.Sprintf(`attachment; filename="%s.csv"`, name),
+	}
+	fields := r.csvFields(params)
+	return r.sendStream(c, MIMETextCSVCharsetUTF8, headers, records, func(w io.Writer) recordWriter {
+		if opts.BOM {
+			w = &bomWriter{w: w}
+		}
+		cw := csv.NewWriter(w)
+		cw.Comma = opts.Comma
+		return &csvWriter{w: cw, fields: fields}
+	})
+}
+
+// csvFields Поля выгрузки: из фильтра, иначе столбцы таблицы. Если поля не известны, то они берутся из первой записи
+func (r *CRUD) csvFields(params *QueryParams) []string {
+	fields := r.Columns(r)
+	if params != nil && params.Filter != nil && len(params.Filter.Fields) > 0 {
+		fields = params.Filter.Fields
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !isExcluded(field, r.Excludes) {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// bomWriter Запись метки порядка байтов UTF-8 перед первыми данными
+type bomWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (b *bomWriter) Write(p []byte) (int, error) {
+	if !b.written {
+		b.written = true
+		if _, err := b.w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+			return 0, err
+		}
+	}
+	return b.w.Write(p)
+}
+
+// isExcluded Проверка поля на исключение из данных
+func isExcluded(field string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if exclude == field {
+			return true
+		}
+	}
+	return false
+}
+
+// csvValue Строковое представление значения ячейки
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
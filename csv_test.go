@@ -0,0 +1,45 @@
+package crud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+func TestReadHandler_CSV(t *testing.T) {
+	tc := newTestContext().SetHeader(consts.HeaderAccept, "text/csv, application/json;q=0.5")
+	ctx := &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("public.users").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, tc.response.contentType, MIMETextCSVCharsetUTF8)
+	assertEq(t, tc.response.headers[consts.HeaderContentDisposition], `attachment; filename="public.users.csv"`)
+	assertEq(t, string(tc.response.body), "id,name\n1,Name\n2,Name2\n")
+
+	tc = newTestContext().SetHeader(consts.HeaderAccept, "text/csv")
+	ctx = &ewa.Context{IContext: tc}
+	r := New(h).SetModelName("users").SetFieldIdName("id").SetExcludes("id").SetCSV(';', true)
+	if err := r.ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, string(tc.response.body), "\xEF\xBB\xBFname\nName\nName2\n")
+
+	// JSON предпочтительнее по весу
+	tc = newTestContext().SetHeader(consts.HeaderAccept, "application/json, text/csv;q=0.9")
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("users").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.contentType, consts.MIMEApplicationJSONCharsetUTF8)
+}
+
+func TestCSVValue(t *testing.T) {
+	assertEq(t, csvValue(nil), "")
+	assertEq(t, csvValue([]byte("a")), "a")
+	assertEq(t, csvValue(1.5), "1.5")
+	assertEq(t, csvValue(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), "2024-01-02T03:04:05Z")
+	assertEq(t, csvValue(map[string]interface{}{"a": 1}), `{"a":1}`)
+}
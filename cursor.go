@@ -0,0 +1,220 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Cursor Курсор постраничного вывода по ключу: значения столбцов сортировки последней (первой) записи страницы
+type Cursor struct {
+	Values []any `json:"v"`
+	// Prev Курсор на предыдущую страницу
+	Prev bool `json:"p,omitempty"`
+}
+
+// Encode Непрозрачное представление курсора для клиента
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor Разбор курсора, переданного клиентом
+func ParseCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	c := new(Cursor)
+	if err = d.Decode(c); err != nil || len(c.Values) == 0 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	// Целые числа сохраняем без потери точности
+	for i, v := range c.Values {
+		switch n := v.(type) {
+		case json.Number:
+			if i64, err := n.Int64(); err == nil {
+				c.Values[i] = i64
+			} else if f64, err := n.Float64(); err == nil {
+				c.Values[i] = f64
+			}
+		case map[string]any, []any:
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+	return c, nil
+}
+
+// Keyset Подготовка фильтра к постраничному выводу по ключу.
+// К сортировке добавляется FieldIdName для однозначного порядка, курсор из Filter.Cursor заменяет Offset.
+// Для курсора на предыдущую страницу сортировка обращается, записи затем разворачивает ReadHandler
+func (r *CRUD) Keyset(f *Filter) error {
+	if f == nil || (f.Limit <= 0 && len(f.Cursor) == 0) {
+		return nil
+	}
+	orders, err := ParseOrders(f.Orders, r.Columns(r))
+	if err != nil {
+		return err
+	}
+	if len(r.FieldIdName) > 0 {
+		var ok bool
+		for _, o := range orders {
+			if o.Field == r.FieldIdName {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			orders = append(orders, Order{Field: r.FieldIdName})
+		}
+	}
+	if len(f.Cursor) > 0 {
+		c, err := ParseCursor(f.Cursor)
+		if err != nil {
+			return err
+		}
+		if len(c.Values) != len(orders) {
+			return fmt.Errorf("cursor does not match orders")
+		}
+		if c.Prev {
+			for i := range orders {
+				orders[i] = orders[i].reverse()
+			}
+		}
+		f.Offset = -1
+		f.cursor = c
+	}
+	f.Orders = make([]string, len(orders))
+	f.keys = nil
+	for i, o := range orders {
+		f.Orders[i] = o.String()
+		if len(f.Fields) > 0 && !isExcluded(o.Field, f.Fields) && !isExcluded(o.Field, f.keys) {
+			f.keys = append(f.keys, o.Field)
+		}
+	}
+	return nil
+}
+
+// Select Поля выборки: Fields и столбцы сортировки для курсоров, которых нет в Fields. Пустой список - все поля
+func (f *Filter) Select() []string {
+	if f == nil || len(f.Fields) == 0 {
+		return nil
+	}
+	return append(append([]string{}, f.Fields...), f.keys...)
+}
+
+// Page Записи страницы в порядке сортировки клиента: записи по курсору назад разворачиваются,
+// а столбцы сортировки, выбранные только для курсоров, исключаются
+func (f *Filter) Page(records RecordsFunc) RecordsFunc {
+	if f == nil || (f.cursor == nil || !f.cursor.Prev) && len(f.keys) == 0 {
+		return records
+	}
+	if f.cursor == nil || !f.cursor.Prev {
+		return func(ctx context.Context, yield func(record Map) error) (int, error) {
+			return records(ctx, func(record Map) error {
+				record.Excludes(f.keys...)
+				return yield(record)
+			})
+		}
+	}
+	return func(ctx context.Context, yield func(record Map) error) (int, error) {
+		// Страница ограничена Limit, поэтому собирается целиком
+		var page Maps
+		status, err := records(ctx, func(record Map) error {
+			page = append(page, record)
+			return nil
+		})
+		if err != nil {
+			return status, err
+		}
+		for i := len(page) - 1; i >= 0; i-- {
+			record := Map(page[i])
+			record.Excludes(f.keys...)
+			if err = yield(record); err != nil {
+				return status, err
+			}
+		}
+		return status, nil
+	}
+}
+
+// Cursors Курсоры на следующую и предыдущую страницы по записям, полученным с фильтром после Keyset
+func (r *CRUD) Cursors(f *Filter, records Maps) (next, prev string) {
+	if f == nil || f.Limit <= 0 || len(records) == 0 {
+		return "", ""
+	}
+	orders, err := ParseOrders(f.Orders, nil)
+	if err != nil {
+		return "", ""
+	}
+	values := func(record map[string]interface{}) []any {
+		a := make([]any, len(orders))
+		for i, o := range orders {
+			a[i] = record[o.Field]
+		}
+		return a
+	}
+	full := len(records) >= f.Limit
+	isPrev := f.cursor != nil && f.cursor.Prev
+	if full || isPrev {
+		next = (&Cursor{Values: values(records[len(records)-1])}).Encode()
+	}
+	if (f.cursor != nil && !isPrev) || (isPrev && full) {
+		prev = (&Cursor{Values: values(records[0]), Prev: true}).Encode()
+	}
+	return next, prev
+}
+
+// seekCondition Условие поиска по курсору. При одинаковом направлении сортировки и rowValues
+// используется сравнение кортежей (a, b) > (?, ?), иначе (a > ?) or (a = ? and b > ?)
+func seekCondition(f *Filter, quote func(string) string, rowValues bool) (string, []any) {
+	if f == nil || f.cursor == nil {
+		return "", nil
+	}
+	orders, err := ParseOrders(f.Orders, nil)
+	if err != nil || len(orders) != len(f.cursor.Values) {
+		return "", nil
+	}
+	cmp := func(o Order) string {
+		if o.Desc {
+			return "<"
+		}
+		return ">"
+	}
+	uniform := true
+	for _, o := range orders {
+		if o.Desc != orders[0].Desc {
+			uniform = false
+			break
+		}
+	}
+	if rowValues && uniform {
+		keys := make([]string, len(orders))
+		marks := make([]string, len(orders))
+		for i, o := range orders {
+			keys[i] = quote(o.Field)
+			marks[i] = "?"
+		}
+		return "(" + strings.Join(keys, ", ") + ") " + cmp(orders[0]) + " (" + strings.Join(marks, ", ") + ")", f.cursor.Values
+	}
+	var (
+		parts  []string
+		values []any
+	)
+	for i, o := range orders {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, quote(orders[j].Field)+" = ?")
+			values = append(values, f.cursor.Values[j])
+		}
+		conds = append(conds, quote(o.Field)+" "+cmp(o)+" ?")
+		values = append(values, f.cursor.Values[i])
+		parts = append(parts, "("+strings.Join(conds, " and ")+")")
+	}
+	return "(" + strings.Join(parts, " or ") + ")", values
+}
@@ -0,0 +1,77 @@
+package crud
+
+import (
+	"testing"
+)
+
+func TestParseCursor(t *testing.T) {
+	c, err := ParseCursor((&Cursor{Values: []any{"Name", int64(9007199254740993)}, Prev: true}).Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, c.Prev, true)
+	assertArrayEq(t, []any{"Name", int64(9007199254740993)}, c.Values)
+
+	for _, s := range []string{"", "!!!", (&Cursor{}).Encode(), (&Cursor{Values: []any{[]any{1}}}).Encode()} {
+		if _, err = ParseCursor(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	r := getCRUD().SetFieldIdName("id")
+
+	f := &Filter{Orders: []string{"name desc"}, Limit: 2, Offset: 10}
+	if err := r.Keyset(f); err != nil {
+		t.Fatal(err)
+	}
+	assertArrayStringEq(t, []string{"name DESC", "id ASC"}, f.Orders)
+	assertEq(t, f.Offset, 10)
+	seek, _ := r.Seek(f)
+	assertEq(t, seek, "")
+
+	records := Maps{{"id": 5, "name": "b"}, {"id": 3, "name": "a"}}
+	next, prev := r.Cursors(f, records)
+	assertEq(t, prev, "")
+
+	// Следующая страница
+	f = &Filter{Orders: []string{"name desc"}, Limit: 2, Offset: 10, Cursor: next}
+	if err := r.Keyset(f); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, f.Offset, -1)
+	seek, values := r.Seek(f)
+	assertEq(t, seek, `(("name" < ?) or ("name" = ? and "id" > ?))`)
+	assertArrayEq(t, []any{"a", "a", int64(3)}, values)
+	paging, _ := r.Paging(f, nil)
+	assertEq(t, paging, `ORDER BY "name" DESC, "id" ASC LIMIT 2`)
+
+	next, prev = r.Cursors(f, Maps{{"id": 2, "name": "a"}})
+	assertEq(t, next, "")
+
+	// Предыдущая страница: сортировка обращается
+	f = &Filter{Orders: []string{"name desc"}, Limit: 2, Cursor: prev}
+	if err := r.Keyset(f); err != nil {
+		t.Fatal(err)
+	}
+	assertArrayStringEq(t, []string{"name ASC", "id DESC"}, f.Orders)
+	seek, values = r.Seek(f)
+	assertEq(t, seek, `(("name" > ?) or ("name" = ? and "id" < ?))`)
+	assertArrayEq(t, []any{"a", "a", int64(2)}, values)
+
+	// Одинаковое направление сортировки - сравнение кортежей
+	f = &Filter{Orders: []string{"name"}, Limit: 2, Cursor: (&Cursor{Values: []any{"a", 1}}).Encode()}
+	if err := r.Keyset(f); err != nil {
+		t.Fatal(err)
+	}
+	seek, _ = r.Seek(f)
+	assertEq(t, seek, `("name", "id") > (?, ?)`)
+	seek, _ = new(SQLServerFormat).Seek(f)
+	assertEq(t, seek, `(([name] > ?) or ([name] = ? and [id] > ?))`)
+
+	f = &Filter{Orders: []string{"name"}, Limit: 2, Cursor: (&Cursor{Values: []any{"a"}}).Encode()}
+	if err := r.Keyset(f); err == nil {
+		t.Fatal("expected cursor mismatch error")
+	}
+}
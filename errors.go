@@ -0,0 +1,169 @@
+package crud
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ewa-go/ewa/consts"
+)
+
+const (
+	MIMEApplicationProblemJSON = "application/problem+json"
+	MIMEApplicationProblemXML  = "application/problem+xml"
+)
+
+// Problem Ошибка в формате RFC 7807. Статус ответа берётся из Status
+type Problem struct {
+	XMLName  xml.Name     `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+	Type     string       `json:"type" xml:"type"`
+	Title    string       `json:"title" xml:"title"`
+	Status   int          `json:"status" xml:"status"`
+	Detail   string       `json:"detail,omitempty" xml:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty" xml:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty" xml:"error,omitempty"`
+
+	err error
+}
+
+// FieldError Ошибка значения поля
+type FieldError struct {
+	Field   string `json:"field" xml:"field"`
+	Message string `json:"message" xml:"message"`
+}
+
+// ProblemType Тип ошибки для сравнения через errors.Is. Константа, поэтому не может быть изменена вызывающим кодом
+type ProblemType string
+
+// Типы ошибок для сравнения через errors.Is
+const (
+	ErrNotFound           ProblemType = "not-found"
+	ErrValidation         ProblemType = "validation"
+	ErrConflict           ProblemType = "conflict"
+	ErrForbidden          ProblemType = "forbidden"
+	ErrPreconditionFailed ProblemType = "precondition-failed"
+	ErrNotAcceptable      ProblemType = "not-acceptable"
+)
+
+// problemTypes Заголовок и статус ответа типов ошибок
+var problemTypes = map[ProblemType]struct {
+	title  string
+	status int
+}{
+	ErrNotFound:           {"Not Found", consts.StatusNotFound},
+	ErrValidation:         {"Validation Failed", consts.StatusBadRequest},
+	ErrConflict:           {"Conflict", consts.StatusConflict},
+	ErrForbidden:          {"Forbidden", consts.StatusForbidden},
+	ErrPreconditionFailed: {"Precondition Failed", consts.StatusPreconditionFailed},
+	ErrNotAcceptable:      {"Not Acceptable", consts.StatusNotAcceptable},
+}
+
+// Problem Новая ошибка типа t без описания
+func (t ProblemType) Problem() *Problem {
+	info := problemTypes[t]
+	return &Problem{Type: string(t), Title: info.title, Status: info.status}
+}
+
+// With Новая ошибка типа t с описанием
+func (t ProblemType) With(detail string) *Problem {
+	return t.Problem().With(detail)
+}
+
+// Wrap Новая ошибка типа t с исходной ошибкой err, её текст становится описанием
+func (t ProblemType) Wrap(err error) *Problem {
+	return t.Problem().Wrap(err)
+}
+
+func (t ProblemType) Error() string {
+	if info, ok := problemTypes[t]; ok {
+		return info.title
+	}
+	return string(t)
+}
+
+// NotFound Запись не найдена
+func NotFound(format string, a ...any) *Problem {
+	return ErrNotFound.With(fmt.Sprintf(format, a...))
+}
+
+// Validation Ошибка проверки данных с ошибками по полям
+func Validation(detail string, fields ...FieldError) *Problem {
+	p := ErrValidation.With(detail)
+	p.Errors = fields
+	return p
+}
+
+// Conflict Нарушение уникальности или ограничений целостности
+func Conflict(format string, a ...any) *Problem {
+	return ErrConflict.With(fmt.Sprintf(format, a...))
+}
+
+// Forbidden Доступ запрещён
+func Forbidden(format string, a ...any) *Problem {
+	return ErrForbidden.With(fmt.Sprintf(format, a...))
+}
+
+// PreconditionFailed Версия записи не совпадает с If-Match
+func PreconditionFailed(format string, a ...any) *Problem {
+	return ErrPreconditionFailed.With(fmt.Sprintf(format, a...))
+}
+
+// NotAcceptable Нет кодировщика для типов из заголовка Accept
+func NotAcceptable(format string, a ...any) *Problem {
+	return ErrNotAcceptable.With(fmt.Sprintf(format, a...))
+}
+
+// With Копия ошибки с описанием
+func (p *Problem) With(detail string) *Problem {
+	c := *p
+	c.Detail = detail
+	c.Errors = nil
+	c.err = nil
+	return &c
+}
+
+// Wrap Копия ошибки с исходной ошибкой err, её текст становится описанием
+func (p *Problem) Wrap(err error) *Problem {
+	c := p.With(err.Error())
+	c.err = err
+	return c
+}
+
+func (p *Problem) Error() string {
+	if len(p.Detail) > 0 {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) Unwrap() error {
+	return p.err
+}
+
+// Is Ошибки одного типа равны, например errors.Is(err, ErrNotFound)
+func (p *Problem) Is(target error) bool {
+	switch t := target.(type) {
+	case ProblemType:
+		return string(t) == p.Type
+	case *Problem:
+		return t.Type == p.Type
+	}
+	return false
+}
+
+// ProblemOf Ошибка в формате RFC 7807. Если err не Problem, то тип about:blank и статус status
+func ProblemOf(err error, status int) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		c := *p
+		if c.Status == 0 {
+			c.Status = status
+		}
+		return &c
+	}
+	if status < 400 {
+		status = consts.StatusInternalServerError
+	}
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: err.Error(), err: err}
+}
@@ -0,0 +1,58 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+func TestProblem(t *testing.T) {
+	err := fmt.Errorf("read: %w", NotFound("record %d not found", 1))
+	assertEq(t, errors.Is(err, ErrNotFound), true)
+	assertEq(t, errors.Is(err, ErrConflict), false)
+	assertEq(t, err.Error(), "read: record 1 not found")
+
+	p := ProblemOf(err, 200)
+	assertEq(t, p.Status, 404)
+	assertEq(t, p.Type, "not-found")
+
+	cause := fmt.Errorf("duplicate key")
+	err = ErrConflict.Wrap(cause)
+	assertEq(t, errors.Is(err, cause), true)
+	assertEq(t, ProblemOf(err, 0).Status, 409)
+
+	p = ErrConflict.With("changed")
+	p.Status = 500
+	assertEq(t, ErrConflict.Problem().Status, 409)
+	assertEq(t, ErrConflict.Error(), "Conflict")
+
+	p = ProblemOf(fmt.Errorf("bad request"), 400)
+	assertEq(t, p.Type, "about:blank")
+	assertEq(t, p.Title, "Bad Request")
+}
+
+func TestResponse_Problem(t *testing.T) {
+	before := func(c *ewa.Context, r *CRUD, i *security.Identity, q *QueryParams, body *Body) (int, error) {
+		return 0, Validation("invalid body", FieldError{Field: "name", Message: "required"})
+	}
+	tc := newTestContext().SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).SetBody(`{"id":1}`)
+	ctx := &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("table").SetFieldIdName("id").CreateHandler(ctx, before, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemJSON)
+	assertEq(t, string(tc.response.body), `{"type":"validation","title":"Validation Failed","status":400,"detail":"invalid body","instance":"/","errors":[{"field":"name","message":"required"}]}`)
+
+	tc = newTestContext().SetHeader(consts.HeaderAccept, consts.MIMEApplicationXML)
+	if err := new(Response).Send(ewa.NewContext(tc), Read, 0, Forbidden("access denied")); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 403)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemXML)
+	assertEq(t, string(tc.response.body), `<problem xmlns="urn:ietf:rfc:7807"><type>forbidden</type><title>Forbidden</title><status>403</status><detail>access denied</detail><instance>/</instance></problem>`)
+}
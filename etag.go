@@ -0,0 +1,89 @@
+package crud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+// SetVersionColumn Установка столбца версии записи. Версия становится ETag записи и увеличивается на 1 при каждом изменении
+func (r *CRUD) SetVersionColumn(column string) *CRUD {
+	r.VersionColumn = column
+	return r
+}
+
+// ETag Слабый тег версии записи: значение столбца версии, иначе хеш записи.
+// Тег слабый, так как он общий для ответов JSON и XML с одной записью
+func (r *CRUD) ETag(record Map) string {
+	if len(r.VersionColumn) > 0 {
+		if v, ok := record[r.VersionColumn]; ok && v != nil {
+			return `W/"` + fmt.Sprint(v) + `"`
+		}
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ifMatch Проверка заголовка If-Match по текущей записи до изменения или удаления.
+// Со столбцом версии в запрос добавляется условие на версию, чтобы изменение между проверкой и записью не потерялось
+func (r *CRUD) ifMatch(c *ewa.Context, params *QueryParams) (int, error) {
+	header := c.Get(consts.HeaderIfMatch)
+	if len(header) == 0 {
+		return consts.StatusOK, nil
+	}
+	if params == nil || params.ID == nil {
+		return consts.StatusBadRequest, Validation(consts.HeaderIfMatch + " requires record id")
+	}
+	status, record, err := r.GetRecord(c, r, params)
+	if err != nil {
+		return status, err
+	}
+	if record == nil {
+		return consts.StatusNotFound, NotFound("%s not found", r.ModelName)
+	}
+	record.Excludes(r.Excludes...)
+	if !matchETag(header, r.ETag(record)) {
+		return consts.StatusPreconditionFailed, PreconditionFailed("%s has been modified", r.ModelName)
+	}
+	if version, ok := record[r.VersionColumn]; ok && version != nil && len(r.VersionColumn) > 0 {
+		// Версия передаётся как есть, без разбора строки: время и байты не искажаются
+		param, err := r.Format(r, &QueryParam{Key: r.VersionColumn, Znak: "=", Value: version, IsQuotes: true, Type: ValueType})
+		if err != nil {
+			return consts.StatusBadRequest, err
+		}
+		params.And(param)
+	}
+	return consts.StatusOK, nil
+}
+
+// lostUpdate Проверка, что запись не изменилась по условию на версию после проверки If-Match
+func (r *CRUD) lostUpdate(c *ewa.Context, result any) error {
+	if len(r.VersionColumn) == 0 || len(c.Get(consts.HeaderIfMatch)) == 0 {
+		return nil
+	}
+	if affected, ok := result.(int64); ok && affected == 0 {
+		return PreconditionFailed("%s has been modified", r.ModelName)
+	}
+	return nil
+}
+
+// matchETag Слабое сравнение тегов из If-Match с тегом записи: версия записи не зависит от формата ответа
+func matchETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+func TestETag(t *testing.T) {
+	r := getCRUD()
+	etag := r.ETag(Map{"id": 1, "name": "Name"})
+	assertEq(t, len(etag), 36)
+	assertEq(t, r.ETag(Map{"name": "Name", "id": 1}), etag)
+	assertEq(t, r.ETag(Map{"id": 1, "name": "Name2"}) != etag, true)
+
+	r.SetVersionColumn("version")
+	assertEq(t, r.ETag(Map{"id": 1, "version": int64(3)}), `W/"3"`)
+
+	assertEq(t, matchETag(`"1", "3"`, `W/"3"`), true)
+	assertEq(t, matchETag(`*`, `W/"3"`), true)
+	assertEq(t, matchETag(`W/"3"`, `W/"3"`), true)
+	assertEq(t, matchETag(`"2"`, `W/"3"`), false)
+}
+
+func TestUpdateHandler_IfMatch(t *testing.T) {
+	d, r := newFakeSQL()
+
+	tc := newTestContext().SetParam("id", "1")
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	etag := tc.response.headers[consts.HeaderETag]
+	assertEq(t, etag, r.ETag(Map{"id": int64(1), "name": "Name1"}))
+
+	update := func(ifMatch string) *testContext {
+		tc := newTestContext().
+			SetParam("id", "1").
+			SetHeader(consts.HeaderIfMatch, ifMatch).
+			SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+			SetBody(`{"name":"Name2"}`)
+		if err := r.UpdateHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		return tc
+	}
+	assertEq(t, update(etag).response.status, 200)
+	tc = update(`"0"`)
+	assertEq(t, tc.response.status, 412)
+	assertEq(t, strings.Contains(string(tc.response.body), `"type":"precondition-failed"`), true)
+
+	// Столбец версии
+	d.queries = nil
+	d.rows = func(query string) ([]string, [][]driver.Value) {
+		return []string{"id", "name", "version"}, [][]driver.Value{{int64(1), []byte("Name1"), int64(3)}}
+	}
+	r.SetVersionColumn("version")
+	assertEq(t, update(`"3"`).response.status, 200)
+	assertEq(t, d.queries[1], `UPDATE "public"."users" SET "name" = $1, "version" = "version" + 1 WHERE "id" = $2 and "version" = $3`)
+
+	// Условие [|] рядом с ID не обходит условие на версию
+	tc = newTestContext().
+		SetParam("id", "1").
+		AddQuery("[|]name", "x").
+		SetHeader(consts.HeaderIfMatch, `"3"`).
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"name":"Name2"}`)
+	if err := r.UpdateHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[len(d.queries)-1], `UPDATE "public"."users" SET "name" = $1, "version" = "version" + 1 WHERE ("id" = $2 or "name" = $3) and "version" = $4`)
+
+	// Запись изменена между проверкой и обновлением
+	d.noRows = true
+	assertEq(t, update(`"3"`).response.status, 412)
+
+	// Версия со временем сравнивается без приведения к строке
+	d.noRows = false
+	version := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.rows = func(query string) ([]string, [][]driver.Value) {
+		return []string{"id", "name", "version"}, [][]driver.Value{{int64(1), []byte("Name1"), version}}
+	}
+	etag = r.ETag(Map{"id": int64(1), "name": "Name1", "version": version})
+	assertEq(t, update(etag).response.status, 200)
+	args := d.args[len(d.args)-1]
+	assertEq(t, args[len(args)-1], any(version))
+
+	tc = newTestContext().SetHeader(consts.HeaderIfMatch, `"3"`).AddQuery("name", "Name1")
+	if err := r.DeleteHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+}
@@ -0,0 +1,113 @@
+package crud
+
+import (
+	"strings"
+)
+
+// Expr Узел дерева условий запроса. Лист содержит условие Param, группа - вложенные узлы Children
+type Expr struct {
+	Param    *QueryParam
+	Group    string
+	IsOR     bool
+	IsNot    bool
+	Children []*Expr
+}
+
+// IsGroup Проверка узла на группу
+func (e *Expr) IsGroup() bool {
+	return e.Param == nil
+}
+
+// Walk Обход дерева в глубину. Если fn возвращает false, то вложенные узлы не обходятся
+func (e *Expr) Walk(fn func(e *Expr) bool) {
+	if e == nil || !fn(e) {
+		return
+	}
+	for _, child := range e.Children {
+		child.Walk(fn)
+	}
+}
+
+// Add Добавление условия в дерево.
+// Без группы условие добавляется в корень и соединяется с предыдущим по IsOR.
+// Первое условие группы задаёт соединение самой группы с предыдущим узлом, последующие - соединение внутри группы.
+// IsNot у условия без группы отрицает само условие, у условия группы - всю группу
+func (e *Expr) Add(param *QueryParam) {
+	if len(param.Group) == 0 {
+		e.Children = append(e.Children, &Expr{Param: param, IsOR: param.IsOR, IsNot: param.IsNot})
+		return
+	}
+	group, created := e.group(param.Group)
+	if created {
+		group.IsOR = param.IsOR
+	}
+	if param.IsNot {
+		group.IsNot = true
+	}
+	leaf := &Expr{Param: param}
+	if !created {
+		leaf.IsOR = param.IsOR
+	}
+	group.Children = append(group.Children, leaf)
+}
+
+// group Найти или создать группу по пути вида g1.g2
+func (e *Expr) group(path string) (node *Expr, created bool) {
+	node = e
+	parts := strings.Split(path, ".")
+	for i := range parts {
+		name := strings.Join(parts[:i+1], ".")
+		var found *Expr
+		for _, child := range node.Children {
+			if child.IsGroup() && child.Group == name {
+				found = child
+				break
+			}
+		}
+		if found == nil {
+			found = &Expr{Group: name}
+			node.Children = append(node.Children, found)
+			created = i == len(parts)-1
+		} else {
+			created = false
+		}
+		node = found
+	}
+	return node, created
+}
+
+// Render Формирование условия по дереву, cond возвращает условие для одного параметра
+func (e *Expr) Render(cond func(param *QueryParam) string) (query string, values []any) {
+	var n int
+	for _, child := range e.Children {
+		var part string
+		if child.IsGroup() {
+			var vals []any
+			part, vals = child.Render(cond)
+			if len(part) == 0 {
+				continue
+			}
+			part = "(" + part + ")"
+			values = append(values, vals...)
+		} else {
+			part = cond(child.Param)
+			values = append(values, child.Param.Value)
+		}
+		if child.IsNot {
+			if !child.IsGroup() {
+				part = "(" + part + ")"
+			}
+			part = "not " + part
+		}
+		if n > 0 {
+			if child.IsOR {
+				query += " or "
+			} else {
+				query += " and "
+			}
+		}
+		query += part
+		n++
+	}
+	return query, values
+}
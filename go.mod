@@ -4,11 +4,9 @@ go 1.21
 
 require (
 	github.com/ewa-go/ewa v0.0.34
+	github.com/ewa-go/jsonschema v0.5.0
+	github.com/iancoleman/orderedmap v0.3.0
 	github.com/lib/pq v1.10.9
 )
 
-require (
-	github.com/ewa-go/jsonschema v0.5.0 // indirect
-	github.com/google/uuid v1.5.0 // indirect
-	github.com/iancoleman/orderedmap v0.3.0 // indirect
-)
+require github.com/google/uuid v1.5.0 // indirect
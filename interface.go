@@ -1,6 +1,7 @@
 package crud
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -19,17 +20,36 @@ type IHandlers interface {
 	GetRecords(c *ewa.Context, r *CRUD, params *QueryParams) (status int, data Maps, total int64, err error)
 	UpdateRecord(c *ewa.Context, r *CRUD, data *Body, params *QueryParams) (status int, result any, err error)
 	DeleteRecord(c *ewa.Context, r *CRUD, params *QueryParams) (status int, result any, err error)
-	/*Audit(action string, c *ewa.Context, r *CRUD)*/
 	Unmarshal(body *Body, contentType string, data []byte) (err error)
 }
 
+// IStreamHandlers Необязательное расширение IHandlers для построчной выдачи записей без буферизации.
+// Выдача продолжается после возврата из обработчика маршрута, поэтому вместо *ewa.Context передаётся контекст запроса ctx,
+// который отменяется при отключении клиента. yield вызывается для каждой записи, ошибка yield прерывает выдачу
+type IStreamHandlers interface {
+	StreamRecords(ctx context.Context, r *CRUD, params *QueryParams, yield func(record Map) error) (status int, err error)
+}
+
+// ILastModifiedHandlers Необязательное расширение IHandlers: время последнего изменения выбираемых записей для заголовка Last-Modified.
+// Нулевое время означает, что время изменения не известно
+type ILastModifiedHandlers interface {
+	LastModified(c *ewa.Context, r *CRUD, params *QueryParams) (status int, modified time.Time, err error)
+}
+
 type IResponse interface {
 	Send(c *ewa.Context, state string, status int, data any) error
 }
 
+// IEncodeResponse Необязательное расширение IResponse: кодирование ответа без отправки для расчёта ETag по телу ответа
+type IEncodeResponse interface {
+	Encode(c *ewa.Context, state string, data any) (contentType string, content []byte, err error)
+}
+
 type IQueryParam interface {
 	Format(r *CRUD, q *QueryParam) (*QueryParam, error)
 	Query(q *QueryParams, columns []string) (string, []any)
+	Paging(f *Filter, columns []string) (string, error)
+	Seek(f *Filter) (string, []any)
 	Cast(value string, q *QueryParam) error
 	Pattern() string
 }
@@ -60,14 +80,6 @@ func (f functions) DeleteRecord(c *ewa.Context, r *CRUD, params *QueryParams) (i
 	return 200, nil, nil
 }
 
-func (f functions) Audit(action string, c *ewa.Context, r *CRUD) {
-	fmt.Println(action)
-	if c.Identity != nil {
-		fmt.Println(c.Identity.Username)
-	}
-	fmt.Println(r.ModelName)
-}
-
 func (f functions) Unmarshal(body *Body, contentType string, data []byte) (err error) {
 	switch contentType {
 	case "application/json", "application/json;utf-8":
@@ -84,7 +96,12 @@ func (f functions) Unmarshal(body *Body, contentType string, data []byte) (err e
 	return nil
 }
 
-type PostgresFormat struct{}
+// baseFormat Общие для всех диалектов приведение типов и разбор значений
+type baseFormat struct{}
+
+type PostgresFormat struct {
+	baseFormat
+}
 
 const (
 	inArray = "&& ARRAY[?]"
@@ -140,9 +157,9 @@ func (p *PostgresFormat) Format(r *CRUD, q *QueryParam) (*QueryParam, error) {
 	case "!array", "!&&":
 		if q.IsArray() {
 			q.Znak = inArray
-			q.Key = fmt.Sprintf(`not "%s"`, q.Key)
-			q.IsQuotes = false
-			return p.setTypeArray(q), nil
+			q = p.setTypeArray(q)
+			q.Znak = "not " + keyMark + " " + q.Znak
+			return q, nil
 		}
 	}
 
@@ -174,6 +191,16 @@ func (p *PostgresFormat) Format(r *CRUD, q *QueryParam) (*QueryParam, error) {
 	return q, nil
 }
 
+// Seek Условие постраничного вывода по курсору (a, b) > (?, ?)
+func (p *PostgresFormat) Seek(f *Filter) (string, []any) {
+	return seekCondition(f, p.quote, true)
+}
+
+// quote Экранирование идентификатора двойными кавычками
+func (p *PostgresFormat) quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
 func (p *PostgresFormat) setTypeArray(q *QueryParam) *QueryParam {
 	if q.Znak == inArray {
 		switch q.DataType {
@@ -196,28 +223,12 @@ func (p *PostgresFormat) setTypeArray(q *QueryParam) *QueryParam {
 	return q
 }
 
-func (*PostgresFormat) Query(q *QueryParams, columns []string) (query string, values []any) {
-	var (
-		params []*QueryParam
-		fields []string
-	)
+func (p *PostgresFormat) Query(q *QueryParams, columns []string) (query string, values []any) {
+	var fields []string
 	// Если нет параметров, то выходим
 	if q.Len() == 0 && q.ID == nil {
 		return "", nil
 	}
-	// Отдельно передаём поле ID
-	if q.ID != nil {
-		params = append(params, q.ID)
-	}
-	// Заполнение параметры адресной строки
-	for key, value := range q.m {
-		if key == AllFieldsParamName || key == ExtraParamName {
-			continue
-		}
-		for _, v := range value {
-			params = append(params, v)
-		}
-	}
 
 	// Формирование полей для поиска везде OR
 	if vals, ok := q.m[AllFieldsParamName]; ok && len(vals) > 0 {
@@ -228,7 +239,7 @@ func (*PostgresFormat) Query(q *QueryParams, columns []string) (query string, va
 				if _, ok = q.m[field]; !ok {
 					value.Key = field
 					if value.IsQuotes {
-						value.Key = `"` + value.Key + `"::text`
+						value.Key = p.quote(value.Key) + "::text"
 					}
 					fields = append(fields, strings.Trim(fmt.Sprintf("%s %s", value.Key, value.Znak), " "))
 					values = append(values, value.Value)
@@ -239,7 +250,7 @@ func (*PostgresFormat) Query(q *QueryParams, columns []string) (query string, va
 				if _, ok = q.m[column]; !ok {
 					value.Key = column
 					if value.IsQuotes {
-						value.Key = `"` + value.Key + `"::text`
+						value.Key = p.quote(value.Key) + "::text"
 					}
 					fields = append(fields, strings.Trim(fmt.Sprintf("%s %s", value.Key, value.Znak), " "))
 					values = append(values, value.Value)
@@ -257,26 +268,22 @@ func (*PostgresFormat) Query(q *QueryParams, columns []string) (query string, va
 		}
 		query = "(" + query + ")"
 	}
-	// Заполняем строку запроса и значения для неё
-	if len(params) > 0 {
-		var v string
-		for i, param := range params {
-			values = append(values, param.Value)
-			var spliter string
-			if i > 0 {
-				spliter = " and "
-			}
-			if param.IsOR {
-				spliter = " or "
-			}
-			if param.IsQuotes {
-				param.Key = `"` + param.Key + `"`
-			}
-			if param.Znak == "like ?" {
-				param.Key += string(param.Type)
-			}
-			v += spliter + strings.Trim(fmt.Sprintf("%s %s", param.Key, param.Znak), " ")
-		}
+	// Заполняем строку запроса и значения для неё по дереву условий: ID, затем параметры в порядке их передачи
+	v, args := q.Where(func(param *QueryParam) string {
+		key := param.Key
+		if param.IsQuotes {
+			key = p.quote(key)
+		}
+		if param.Znak == "like ?" {
+			key += string(param.Type)
+		}
+		if strings.Contains(param.Znak, keyMark) {
+			return strings.ReplaceAll(param.Znak, keyMark, key)
+		}
+		return strings.Trim(fmt.Sprintf("%s %s", key, param.Znak), " ")
+	})
+	values = append(values, args...)
+	if len(v) > 0 {
 		if len(query) > 0 {
 			query += " and " + v
 		} else {
@@ -295,8 +302,37 @@ func (*PostgresFormat) Query(q *QueryParams, columns []string) (query string, va
 	return query, vals
 }
 
+// Paging Сортировка и постраничный вывод ORDER BY ... LIMIT ... OFFSET ...
+func (p *PostgresFormat) Paging(f *Filter, columns []string) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	orders, err := ParseOrders(f.Orders, columns)
+	if err != nil {
+		return "", err
+	}
+	var query []string
+	if len(orders) > 0 {
+		a := make([]string, len(orders))
+		for i, o := range orders {
+			a[i] = quoteIdent(o.Field) + " " + o.Direction()
+			if len(o.Nulls) > 0 {
+				a[i] += " NULLS " + o.Nulls
+			}
+		}
+		query = append(query, "ORDER BY "+strings.Join(a, ", "))
+	}
+	if f.Limit > 0 {
+		query = append(query, "LIMIT "+strconv.Itoa(f.Limit))
+	}
+	if f.Offset > 0 {
+		query = append(query, "OFFSET "+strconv.Itoa(f.Offset))
+	}
+	return strings.Join(query, " "), nil
+}
+
 // Cast Приведение переменной к типу данных
-func (p *PostgresFormat) Cast(value string, q *QueryParam) (err error) {
+func (p *baseFormat) Cast(value string, q *QueryParam) (err error) {
 
 	if q.DataType == "" {
 		switch strings.ToLower(value) {
@@ -421,7 +457,7 @@ func (p *PostgresFormat) Cast(value string, q *QueryParam) (err error) {
 }
 
 // IsArray Проверка на массив
-func (*PostgresFormat) IsArray(value string) ([]string, bool) {
+func (*baseFormat) IsArray(value string) ([]string, bool) {
 	rgx := regexp.MustCompile(`^\[(.+)]$`)
 	if rgx.MatchString(value) {
 		matches := rgx.FindStringSubmatch(value)
@@ -432,7 +468,7 @@ func (*PostgresFormat) IsArray(value string) ([]string, bool) {
 	return nil, false
 }
 
-func (*PostgresFormat) IsRange(znak, value string) ([]string, bool) {
+func (*baseFormat) IsRange(znak, value string) ([]string, bool) {
 	if znak == ":" {
 		rgx := regexp.MustCompile(`^\[(.+)\|(.+)]$`)
 		if rgx.MatchString(value) {
@@ -445,7 +481,7 @@ func (*PostgresFormat) IsRange(znak, value string) ([]string, bool) {
 	return nil, false
 }
 
-func (*PostgresFormat) SetInt32Array(array []string) (a []int) {
+func (*baseFormat) SetInt32Array(array []string) (a []int) {
 	for _, v := range array {
 		if value, err := strconv.Atoi(v); err == nil {
 			a = append(a, value)
@@ -454,7 +490,7 @@ func (*PostgresFormat) SetInt32Array(array []string) (a []int) {
 	return a
 }
 
-func (*PostgresFormat) SetInt64Array(array []string) (a []int64) {
+func (*baseFormat) SetInt64Array(array []string) (a []int64) {
 	for _, v := range array {
 		if value, err := strconv.ParseInt(v, 10, 64); err == nil {
 			a = append(a, value)
@@ -463,7 +499,7 @@ func (*PostgresFormat) SetInt64Array(array []string) (a []int64) {
 	return a
 }
 
-func (*PostgresFormat) SetUIntArray(array []string) (a []uint64) {
+func (*baseFormat) SetUIntArray(array []string) (a []uint64) {
 	for _, v := range array {
 		if value, err := strconv.ParseUint(v, 10, 32); err == nil {
 			a = append(a, value)
@@ -472,7 +508,7 @@ func (*PostgresFormat) SetUIntArray(array []string) (a []uint64) {
 	return a
 }
 
-func (*PostgresFormat) SetUInt64Array(array []string) (a []uint64) {
+func (*baseFormat) SetUInt64Array(array []string) (a []uint64) {
 	for _, v := range array {
 		if value, err := strconv.ParseUint(v, 10, 64); err == nil {
 			a = append(a, value)
@@ -481,7 +517,7 @@ func (*PostgresFormat) SetUInt64Array(array []string) (a []uint64) {
 	return a
 }
 
-func (*PostgresFormat) SetFloat32Array(array []string) (a []float32) {
+func (*baseFormat) SetFloat32Array(array []string) (a []float32) {
 	for _, v := range array {
 		if value, err := strconv.ParseFloat(v, 32); err == nil {
 			a = append(a, float32(value))
@@ -490,7 +526,7 @@ func (*PostgresFormat) SetFloat32Array(array []string) (a []float32) {
 	return a
 }
 
-func (*PostgresFormat) SetFloat64Array(array []string) (a []float64) {
+func (*baseFormat) SetFloat64Array(array []string) (a []float64) {
 	for _, v := range array {
 		if value, err := strconv.ParseFloat(v, 64); err == nil {
 			a = append(a, value)
@@ -499,7 +535,7 @@ func (*PostgresFormat) SetFloat64Array(array []string) (a []float64) {
 	return a
 }
 
-func (*PostgresFormat) SetTimeArray(array []string, layout string) (a []time.Time) {
+func (*baseFormat) SetTimeArray(array []string, layout string) (a []time.Time) {
 	for _, v := range array {
 		if value, err := time.Parse(layout, v); err == nil {
 			a = append(a, value)
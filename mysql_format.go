@@ -0,0 +1,286 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MySQLFormat Форматирование query параметров для MySQL/MariaDB.
+// Операторы [&&] и [array] используют JSON_OVERLAPS: MySQL 8.0.17+ или MariaDB 10.9+
+type MySQLFormat struct {
+	baseFormat
+}
+
+// Сопоставления для LIKE и REGEXP: с учётом регистра и без
+const (
+	binCollation = "utf8mb4_bin"
+	ciCollation  = "utf8mb4_general_ci"
+)
+
+// keyMark Метка подстановки имени поля в выражение оператора
+const keyMark = "{key}"
+
+var jsonPathRegexp = regexp.MustCompile(`^[\w.\[\]]+$`)
+
+func (m *MySQLFormat) Pattern() string {
+	return `\[(->|->>|>|<|>-|<-|!|<>|array|&&|!array|!&&|~|!~|~\*|!~\*|\+|!\+|%|!%|:|[aA-zZ]+)]$`
+}
+
+func (m *MySQLFormat) Format(r *CRUD, q *QueryParam) (*QueryParam, error) {
+
+	not := strings.HasPrefix(q.Znak, "!")
+	switch q.Znak {
+	case "!":
+		q.Znak = "!="
+	case ">-":
+		q.Znak = ">="
+	case "<-":
+		q.Znak = "<="
+	case "%":
+		// like и REGEXP в MySQL зависят от сопоставления, поэтому сравниваем побайтно как в postgres
+		q.Znak = collate(binCollation) + " LIKE ?"
+		return q, nil
+	case "!%":
+		q.Znak = collate(binCollation) + " NOT LIKE ?"
+		return q, nil
+	case "~":
+		q.Znak = collate(binCollation) + " REGEXP ?"
+		return q, nil
+	case "!~":
+		q.Znak = collate(binCollation) + " NOT REGEXP ?"
+		return q, nil
+	case "~*":
+		q.Znak = collate(ciCollation) + " REGEXP ?"
+		return q, nil
+	case "!~*":
+		q.Znak = collate(ciCollation) + " NOT REGEXP ?"
+		return q, nil
+	case "+", "!+":
+		if v, ok := q.Value.(string); ok {
+			q.Value = similarToRegexp(v)
+		}
+		q.Znak = collate(binCollation) + " REGEXP ?"
+		if not {
+			q.Znak = collate(binCollation) + " NOT REGEXP ?"
+		}
+		return q, nil
+	case "->", "->>":
+		v, ok := q.Value.(string)
+		if !ok {
+			break
+		}
+		a := strings.SplitN(v, "=", 2)
+		if len(a) != 2 {
+			break
+		}
+		qf, err := r.QueryFormat(a[0], a[1])
+		if err != nil {
+			return nil, err
+		}
+		if !jsonPathRegexp.MatchString(qf.Key) {
+			return nil, fmt.Errorf("invalid json path %s", qf.Key)
+		}
+		expr := "JSON_EXTRACT(" + keyMark + ", '$." + qf.Key + "')"
+		if q.Znak == "->>" {
+			expr = "JSON_UNQUOTE(" + expr + ")"
+		}
+		q.Znak = expr + " " + qf.Znak
+		if strings.Contains(qf.Znak, keyMark) {
+			q.Znak = strings.ReplaceAll(qf.Znak, keyMark, expr)
+		}
+		q.Value = qf.Value
+		q.Type = qf.Type
+		return q, nil
+	case "array", "&&", "!array", "!&&":
+		if !q.IsArray() {
+			break
+		}
+		b, err := json.Marshal(q.Value)
+		if err != nil {
+			return nil, err
+		}
+		q.Value = string(b)
+		q.Type = ValueType
+		q.Znak = "JSON_OVERLAPS(" + keyMark + ", ?)"
+		if not {
+			q.Znak = "NOT " + q.Znak
+		}
+		return q, nil
+	}
+
+	if q.Value == nil {
+		switch q.Znak {
+		case "=":
+			q.Znak = "is null"
+		case "!=", "<>":
+			q.Znak = "is not null"
+		}
+		return q, nil
+	}
+	if q.IsArray() {
+		switch q.Znak {
+		case "=":
+			q.Znak = "in(?)"
+		case "!=", "<>":
+			q.Znak = "not in(?)"
+		}
+		return q, nil
+	}
+	if q.IsRange() {
+		q.Znak = "between ? and ?"
+		return q, nil
+	}
+
+	q.Znak += " ?"
+
+	return q, nil
+}
+
+func (m *MySQLFormat) Query(q *QueryParams, columns []string) (query string, values []any) {
+	var fields []string
+	// Если нет параметров, то выходим
+	if q.Len() == 0 && q.ID == nil {
+		return "", nil
+	}
+
+	// Формирование полей для поиска везде OR
+	if vals, ok := q.m[AllFieldsParamName]; ok && len(vals) > 0 {
+		value := vals[0]
+		if q.Filter != nil && len(q.Filter.Fields) > 0 {
+			columns = q.Filter.Fields
+		}
+		for _, column := range columns {
+			if _, ok = q.m[column]; !ok {
+				key := m.quote(column)
+				if value.IsQuotes && !strings.Contains(value.Znak, keyMark) {
+					key = "CAST(" + key + " AS CHAR)"
+				}
+				fields = append(fields, m.condition(key, value.Znak))
+				values = append(values, value.Value)
+			}
+		}
+	}
+	if len(fields) > 0 {
+		query = "(" + strings.Join(fields, " or ") + ")"
+	}
+	// Заполняем строку запроса и значения для неё по дереву условий
+	v, args := q.Where(func(param *QueryParam) string {
+		key := param.Key
+		if param.IsQuotes {
+			key = m.quote(key)
+		}
+		return m.condition(key, param.Znak)
+	})
+	values = append(values, args...)
+	if len(v) > 0 {
+		if len(query) > 0 {
+			query += " and " + v
+		} else {
+			query = v
+		}
+	}
+
+	// Избавляемся от nil значений
+	var vals []any
+	for _, value := range values {
+		if value != nil {
+			vals = append(vals, value)
+		}
+	}
+
+	return query, vals
+}
+
+// Paging Сортировка и постраничный вывод ORDER BY ... LIMIT ... OFFSET ...
+// NULLS FIRST/LAST в MySQL нет, поэтому положение null задаётся дополнительной сортировкой
+func (m *MySQLFormat) Paging(f *Filter, columns []string) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	orders, err := ParseOrders(f.Orders, columns)
+	if err != nil {
+		return "", err
+	}
+	var query []string
+	if len(orders) > 0 {
+		var a []string
+		for _, o := range orders {
+			key := m.quote(o.Field)
+			switch o.Nulls {
+			case "FIRST":
+				a = append(a, key+" IS NULL DESC")
+			case "LAST":
+				a = append(a, key+" IS NULL ASC")
+			}
+			a = append(a, key+" "+o.Direction())
+		}
+		query = append(query, "ORDER BY "+strings.Join(a, ", "))
+	}
+	switch {
+	case f.Limit > 0:
+		query = append(query, "LIMIT "+strconv.Itoa(f.Limit))
+	case f.Offset > 0:
+		// OFFSET без LIMIT в MySQL недопустим
+		query = append(query, "LIMIT 18446744073709551615")
+	}
+	if f.Offset > 0 {
+		query = append(query, "OFFSET "+strconv.Itoa(f.Offset))
+	}
+	return strings.Join(query, " "), nil
+}
+
+// Seek Условие постраничного вывода по курсору (a, b) > (?, ?)
+func (m *MySQLFormat) Seek(f *Filter) (string, []any) {
+	return seekCondition(f, m.quote, true)
+}
+
+// condition Подстановка поля в выражение оператора
+func (m *MySQLFormat) condition(key, znak string) string {
+	if strings.Contains(znak, keyMark) {
+		return strings.ReplaceAll(znak, keyMark, key)
+	}
+	return strings.Trim(key+" "+znak, " ")
+}
+
+// quote Экранирование идентификатора обратными кавычками
+func (m *MySQLFormat) quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// DefaultPlaceholder Плейсхолдеры ?
+func (m *MySQLFormat) DefaultPlaceholder() Placeholder {
+	return PlaceholderQuestion
+}
+
+// ExpandArrays Драйверы MySQL не передают массивы, поэтому in(?) раскрывается поэлементно
+func (m *MySQLFormat) ExpandArrays() bool {
+	return true
+}
+
+// collate Поле в виде строки utf8mb4 с сопоставлением collation
+func collate(collation string) string {
+	return "CONVERT(" + keyMark + " USING utf8mb4) COLLATE " + collation
+}
+
+// similarToRegexp Перевод шаблона similar to в регулярное выражение
+func similarToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^(")
+	for _, ch := range pattern {
+		switch ch {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		case '.':
+			b.WriteString(`\.`)
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	b.WriteString(")$")
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package crud
+
+import (
+	"testing"
+)
+
+func getMySQLCRUD() *CRUD {
+	return New(new(functions)).SetIQueryParam(new(MySQLFormat))
+}
+
+func TestMySQLParams(t *testing.T) {
+	r := getMySQLCRUD()
+	q := &QueryParams{}
+	q.ID = QueryFormat(r, "id", "1::int")
+	q.Set("name", QueryFormat(r, "name[!]", "null"))
+	query, values := r.Query(q, r.Columns(r))
+	assertEq(t, query, "`id` = ? and `name` is not null")
+	assertArrayEq(t, []any{1}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "2::int")
+	q.Set("*", QueryFormat(r, "*[%]", "Зна%"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, "(CONVERT(`id` USING utf8mb4) COLLATE utf8mb4_bin LIKE ? or CONVERT(`name` USING utf8mb4) COLLATE utf8mb4_bin LIKE ?) and `id` = ?")
+	assertArrayEq(t, []any{"Зна%", "Зна%", 2}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "3::int")
+	q.Set("*", QueryFormat(r, "*", "Значение"))
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, "(CAST(`id` AS CHAR) = ? or CAST(`name` AS CHAR) = ?) and `id` = ?")
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "4::int")
+	q.Set("name", QueryFormat(r, "name[~*]", "^Им"))
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, "`id` = ? and CONVERT(`name` USING utf8mb4) COLLATE utf8mb4_general_ci REGEXP ?")
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "5::int")
+	q.Set("name", QueryFormat(r, "name[!+]", "Им%"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, "`id` = ? and CONVERT(`name` USING utf8mb4) COLLATE utf8mb4_bin NOT REGEXP ?")
+	assertArrayEq(t, []any{5, "^(Им.*)$"}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "6::int")
+	q.Set("tags", QueryFormat(r, "tags[!&&]", "[success,warning]"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, "`id` = ? and NOT JSON_OVERLAPS(`tags`, ?)")
+	assertArrayEq(t, []any{6, `["success","warning"]`}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "7::int")
+	q.Set("name", QueryFormat(r, "name", "[a,b]"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, "`id` = ? and `name` in(?,?)")
+	assertArrayEq(t, []any{7, "a", "b"}, values)
+
+	// Диапазон и массив передаются отдельными значениями
+	q = &QueryParams{}
+	q.Set("age", QueryFormat(r, "age[:]", "[1|5]::int"))
+	q.Set("name", QueryFormat(r, "name", "[a,b]"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, "`age` between ? and ? and `name` in(?,?)")
+	assertArrayEq(t, []any{1, 5, "a", "b"}, values)
+}
+
+func TestMySQLJSON(t *testing.T) {
+	r := getMySQLCRUD()
+	q := &QueryParams{}
+	q.ID = QueryFormat(r, "id", "1::int")
+	q.Set("result", QueryFormat(r, "result[->>]", "type=2"))
+	query, values := r.Query(q, r.Columns(r))
+	assertEq(t, query, "`id` = ? and JSON_UNQUOTE(JSON_EXTRACT(`result`, '$.type')) = ?")
+	assertArrayEq(t, []any{1, "2"}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "2::int")
+	q.Set("result", QueryFormat(r, "result[->]", "type[%]=2%"))
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, "`id` = ? and CONVERT(JSON_EXTRACT(`result`, '$.type') USING utf8mb4) COLLATE utf8mb4_bin LIKE ?")
+
+	if _, err := r.QueryFormat("result[->>]", "ty'pe=2"); err == nil {
+		t.Fatal("expected json path error")
+	}
+}
@@ -0,0 +1,427 @@
+package crud
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/jsonschema"
+	"github.com/iancoleman/orderedmap"
+)
+
+// OpenAPI Документ OpenAPI 3.1 для ресурсов CRUD. Схемы описываются JSON Schema 2020-12
+type OpenAPI struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       OpenAPIInfo                 `json:"info"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents           `json:"components"`
+}
+
+// OpenAPIInfo Описание API
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIPathItem Операции пути
+type OpenAPIPathItem struct {
+	Get        *OpenAPIOperation   `json:"get,omitempty"`
+	Post       *OpenAPIOperation   `json:"post,omitempty"`
+	Put        *OpenAPIOperation   `json:"put,omitempty"`
+	Delete     *OpenAPIOperation   `json:"delete,omitempty"`
+	Parameters []*OpenAPIParameter `json:"parameters,omitempty"`
+}
+
+// OpenAPIOperation Операция над ресурсом
+type OpenAPIOperation struct {
+	Tags        []string                    `json:"tags,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	OperationID string                      `json:"operationId,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter Параметр пути, адресной строки или заголовок
+type OpenAPIParameter struct {
+	Ref         string                       `json:"$ref,omitempty"`
+	Name        string                       `json:"name,omitempty"`
+	In          string                       `json:"in,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Style       string                       `json:"style,omitempty"`
+	Explode     *bool                        `json:"explode,omitempty"`
+	Schema      *jsonschema.Schema           `json:"schema,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIRequestBody Тело запроса
+type OpenAPIRequestBody struct {
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIMediaType Схема содержимого
+type OpenAPIMediaType struct {
+	Schema *jsonschema.Schema `json:"schema,omitempty"`
+}
+
+// OpenAPIResponse Ответ операции
+type OpenAPIResponse struct {
+	Ref         string                       `json:"$ref,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Headers     map[string]*OpenAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIHeader Заголовок ответа
+type OpenAPIHeader struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Schema      *jsonschema.Schema `json:"schema,omitempty"`
+}
+
+// OpenAPIComponents Общие схемы, параметры, заголовки и ответы
+type OpenAPIComponents struct {
+	Schemas    map[string]*jsonschema.Schema `json:"schemas,omitempty"`
+	Parameters map[string]*OpenAPIParameter  `json:"parameters,omitempty"`
+	Headers    map[string]*OpenAPIHeader     `json:"headers,omitempty"`
+	Responses  map[string]*OpenAPIResponse   `json:"responses,omitempty"`
+}
+
+// NewOpenAPI Инициализация документа с общими компонентами: фильтр, конверт ответа, ошибка RFC 7807 и заголовки
+func NewOpenAPI(title, version string) *OpenAPI {
+	explode := true
+	return &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    OpenAPIInfo{Title: title, Version: version},
+		Paths:   map[string]*OpenAPIPathItem{},
+		Components: OpenAPIComponents{
+			Schemas: map[string]*jsonschema.Schema{
+				"Filter": objectSchema("Фильтр запроса GET", [][2]any{
+					{"fields", arraySchema(&jsonschema.Schema{Type: "string"}, "Поля выборки")},
+					{"orders", arraySchema(&jsonschema.Schema{Type: "string"}, `Сортировка "field [asc|desc] [nulls first|last]"`)},
+					{"limit", &jsonschema.Schema{Type: "integer", Description: "Число строк"}},
+					{"offset", &jsonschema.Schema{Type: "integer", Description: "Смещение"}},
+					{"cursor", &jsonschema.Schema{Type: "string", Description: "Курсор из заголовков Next-Cursor или Prev-Cursor"}},
+					{"vars", &jsonschema.Schema{Type: "object", Description: "Переменные запроса"}},
+				}),
+				"Envelope": objectSchema("Результат изменения записей", [][2]any{
+					{"ok", &jsonschema.Schema{Type: "boolean"}},
+					{"state", &jsonschema.Schema{Type: "string", Enum: []any{Created, Updated, Deleted, Restored}}},
+					{"datetime", &jsonschema.Schema{Type: "string", Format: "date-time"}},
+					{"data", &jsonschema.Schema{Description: "Результат обработчика"}},
+				}),
+				"FieldError": objectSchema("Ошибка значения поля", [][2]any{
+					{"field", &jsonschema.Schema{Type: "string"}},
+					{"message", &jsonschema.Schema{Type: "string"}},
+				}),
+				"Problem": objectSchema("Ошибка в формате RFC 7807", [][2]any{
+					{"type", &jsonschema.Schema{Type: "string"}},
+					{"title", &jsonschema.Schema{Type: "string"}},
+					{"status", &jsonschema.Schema{Type: "integer"}},
+					{"detail", &jsonschema.Schema{Type: "string"}},
+					{"instance", &jsonschema.Schema{Type: "string"}},
+					{"errors", arraySchema(schemaRef("FieldError"), "")},
+				}),
+			},
+			Parameters: map[string]*OpenAPIParameter{
+				"Filter": {
+					Name: filterParamName, In: "query",
+					Description: "Фильтр в формате json, заменяет тело запроса GET",
+					Content:     map[string]*OpenAPIMediaType{consts.MIMEApplicationJSON: {Schema: schemaRef("Filter")}},
+				},
+				"Conditions": {
+					Name: "conditions", In: "query", Style: "form", Explode: &explode,
+					Description: "Условия по столбцам в виде key[znak]=value, см. описание операции",
+					Schema:      &jsonschema.Schema{Type: "object", AdditionalProperties: &jsonschema.Schema{Type: "string"}},
+				},
+				"TableInfo": {
+					Name: HeaderTableInfo, In: "header",
+					Description: "Вернуть описание столбцов: full или имена столбцов через запятую",
+					Schema:      &jsonschema.Schema{Type: "string"},
+				},
+				"XContentType": {
+					Name: HeaderXContentType, In: "header",
+					Description: "array - тело запроса содержит массив записей",
+					Schema:      &jsonschema.Schema{Type: "string", Enum: []any{"array"}},
+				},
+				"IfMatch": {
+					Name: consts.HeaderIfMatch, In: "header",
+					Description: "ETag записи, при несовпадении возвращается 412",
+					Schema:      &jsonschema.Schema{Type: "string"},
+				},
+				"IfNoneMatch": {
+					Name: consts.HeaderIfNoneMatch, In: "header",
+					Description: "ETag ответа, при совпадении возвращается 304",
+					Schema:      &jsonschema.Schema{Type: "string"},
+				},
+				"IfModifiedSince": {
+					Name: consts.HeaderIfModifiedSince, In: "header",
+					Description: "Время последнего получения, если записи не изменились, то возвращается 304",
+					Schema:      &jsonschema.Schema{Type: "string"},
+				},
+				"Prefer": {
+					Name: HeaderPrefer, In: "header",
+					Description: "Способ подсчёта общего количества записей",
+					Schema:      &jsonschema.Schema{Type: "string", Enum: []any{"count=" + string(CountExact), "count=" + string(CountEstimated), "count=" + string(CountNone)}},
+				},
+			},
+			Headers: map[string]*OpenAPIHeader{
+				HeaderTotal:               {Description: "Общее количество записей", Schema: &jsonschema.Schema{Type: "integer"}},
+				consts.HeaderETag:         {Description: "Версия записи или ответа", Schema: &jsonschema.Schema{Type: "string"}},
+				consts.HeaderLastModified: {Description: "Время последнего изменения записей", Schema: &jsonschema.Schema{Type: "string"}},
+				HeaderNextCursor:          {Description: "Курсор следующей страницы", Schema: &jsonschema.Schema{Type: "string"}},
+				HeaderPrevCursor:          {Description: "Курсор предыдущей страницы", Schema: &jsonschema.Schema{Type: "string"}},
+				HeaderPreferenceApplied:   {Description: "Применённый способ подсчёта", Schema: &jsonschema.Schema{Type: "string"}},
+			},
+			Responses: map[string]*OpenAPIResponse{
+				"NotModified": {Description: "Записи не изменились"},
+				"Problem": {
+					Description: "Ошибка",
+					Content: map[string]*OpenAPIMediaType{
+						MIMEApplicationProblemJSON: {Schema: schemaRef("Problem")},
+						MIMEApplicationProblemXML:  {Schema: schemaRef("Problem")},
+					},
+				},
+			},
+		},
+	}
+}
+
+// AddResource Добавление операций ресурса: path для списка записей и path/{FieldIdName} для одной записи.
+// Схема записи берётся из JSON схемы модели, иначе из описания столбцов обработчика
+func (o *OpenAPI) AddResource(path string, r *CRUD) error {
+	name := fileNameRegexp.ReplaceAllString(r.ModelName, "_")
+	if len(name) == 0 {
+		return fmt.Errorf("openapi: model name is not set for %s", path)
+	}
+	schema := r.GetSchema()
+	if schema == nil {
+		info, err := r.TableInfo()
+		if err != nil {
+			return fmt.Errorf("openapi: %s: %w", name, err)
+		}
+		schema = columnsSchema(info)
+	}
+	o.Components.Schemas[name] = schema
+	record := schemaRef(name)
+	records := arraySchema(record, "")
+
+	var tableType []*OpenAPIParameter
+	if r.TableTypes != nil {
+		param := &OpenAPIParameter{Name: HeaderTableType, In: "header", Description: "Тип таблицы", Schema: &jsonschema.Schema{Type: "string"}}
+		for _, t := range r.TableTypes {
+			param.Schema.Enum = append(param.Schema.Enum, t.Key)
+			if t.IsDefault {
+				param.Schema.Default = t.Key
+			}
+		}
+		tableType = append(tableType, param)
+	}
+	withDeleted := tableType
+	if r.SoftDelete != nil {
+		withDeleted = append(append([]*OpenAPIParameter{}, tableType...), &OpenAPIParameter{
+			Name: HeaderWithDeleted, In: "header", Description: "Получение удалённых записей: true - всех, only - только удалённых",
+			Schema: &jsonschema.Schema{Type: "string", Enum: []any{"true", "only"}},
+		})
+	}
+	conditions := fmt.Sprintf("Условия по столбцам задаются параметрами адресной строки key[znak]=value, например `?name[%%]=A%%&id[>]=10`. "+
+		"Без оператора используется равенство, значение [a,b] - список, [a|b] - диапазон. Операторы: %s", strings.Join(operators(r.Pattern()), " "))
+
+	collection := &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			Tags: []string{name}, OperationID: "list_" + name,
+			Summary: "Получение записей", Description: conditions,
+			Parameters: append([]*OpenAPIParameter{parameterRef("Filter"), parameterRef("Conditions"), parameterRef("TableInfo"), parameterRef("Prefer"),
+				parameterRef("IfNoneMatch"), parameterRef("IfModifiedSince")}, withDeleted...),
+			Responses: map[string]*OpenAPIResponse{
+				"200": {
+					Description: "Записи",
+					Headers: map[string]*OpenAPIHeader{
+						HeaderTotal:               headerRef(HeaderTotal),
+						consts.HeaderETag:         headerRef(consts.HeaderETag),
+						consts.HeaderLastModified: headerRef(consts.HeaderLastModified),
+						HeaderNextCursor:          headerRef(HeaderNextCursor),
+						HeaderPrevCursor:          headerRef(HeaderPrevCursor),
+						HeaderPreferenceApplied:   headerRef(HeaderPreferenceApplied),
+					},
+					Content: map[string]*OpenAPIMediaType{
+						consts.MIMEApplicationJSON: {Schema: records},
+						consts.MIMEApplicationXML:  {Schema: records},
+						"text/csv":                 {Schema: &jsonschema.Schema{Type: "string"}},
+						MIMEApplicationNDJSON:      {Schema: record},
+					},
+				},
+				"304":     responseRef("NotModified"),
+				"default": responseRef("Problem"),
+			},
+		},
+		Post: &OpenAPIOperation{
+			Tags: []string{name}, OperationID: "create_" + name,
+			Summary:     "Создание записей",
+			Parameters:  append([]*OpenAPIParameter{parameterRef("XContentType")}, tableType...),
+			RequestBody: recordsBody(record, records),
+			Responses:   envelopeResponses("201"),
+		},
+		Put: &OpenAPIOperation{
+			Tags: []string{name}, OperationID: "update_" + name,
+			Summary: "Изменение записей по условиям", Description: conditions,
+			Parameters:  append([]*OpenAPIParameter{parameterRef("Conditions"), parameterRef("XContentType")}, tableType...),
+			RequestBody: recordsBody(record, records),
+			Responses:   envelopeResponses("200"),
+		},
+		Delete: &OpenAPIOperation{
+			Tags: []string{name}, OperationID: "delete_" + name,
+			Summary: "Удаление записей по условиям", Description: conditions,
+			Parameters: append([]*OpenAPIParameter{parameterRef("Conditions")}, tableType...),
+			Responses:  envelopeResponses("200"),
+		},
+	}
+	o.Paths[path] = collection
+	if len(r.FieldIdName) == 0 {
+		return nil
+	}
+
+	ifMatch := append([]*OpenAPIParameter{parameterRef("IfMatch")}, tableType...)
+	item := &OpenAPIPathItem{
+		Parameters: []*OpenAPIParameter{{
+			Name: r.FieldIdName, In: "path", Required: true,
+			Description: "Идентификатор записи, значение [a,b] - список, [a|b] - диапазон",
+			Schema:      &jsonschema.Schema{Type: "string"},
+		}},
+		Get: &OpenAPIOperation{
+			Tags: []string{name}, OperationID: "get_" + name,
+			Summary:    "Получение записи",
+			Parameters: append([]*OpenAPIParameter{parameterRef("IfNoneMatch"), parameterRef("IfModifiedSince")}, withDeleted...),
+			Responses: map[string]*OpenAPIResponse{
+				"200": {
+					Description: "Запись",
+					Headers: map[string]*OpenAPIHeader{
+						consts.HeaderETag:         headerRef(consts.HeaderETag),
+						consts.HeaderLastModified: headerRef(consts.HeaderLastModified),
+					},
+					Content: map[string]*OpenAPIMediaType{
+						consts.MIMEApplicationJSON: {Schema: record},
+						consts.MIMEApplicationXML:  {Schema: record},
+					},
+				},
+				"304":     responseRef("NotModified"),
+				"default": responseRef("Problem"),
+			},
+		},
+		Put: &OpenAPIOperation{
+			Tags: []string{name}, OperationID: "update_" + name + "_by_id",
+			Summary:     "Изменение записи",
+			Parameters:  ifMatch,
+			RequestBody: &OpenAPIRequestBody{Required: true, Content: map[string]*OpenAPIMediaType{consts.MIMEApplicationJSON: {Schema: record}}},
+			Responses:   envelopeResponses("200"),
+		},
+		Delete: &OpenAPIOperation{
+			Tags: []string{name}, OperationID: "delete_" + name + "_by_id",
+			Summary:    "Удаление записи",
+			Parameters: ifMatch,
+			Responses:  envelopeResponses("200"),
+		},
+	}
+	o.Paths[strings.TrimSuffix(path, "/")+"/{"+r.FieldIdName+"}"] = item
+	return nil
+}
+
+// columnsSchema Схема записи по описанию столбцов
+func columnsSchema(info *TableInfo) *jsonschema.Schema {
+	s := &jsonschema.Schema{Type: "object", Properties: orderedmap.New()}
+	for _, column := range info.Columns {
+		property := columnSchema(column.Type)
+		property.Description = column.Description
+		s.Properties.Set(column.Name, property)
+	}
+	return s
+}
+
+// columnSchema Схема значения по типу столбца бд. Если тип не известен, то допускается любое значение
+func columnSchema(columnType string) *jsonschema.Schema {
+	t := strings.ToLower(columnType)
+	switch {
+	case len(t) == 0:
+		return &jsonschema.Schema{}
+	case t == "array" || strings.HasSuffix(t, "[]"):
+		return &jsonschema.Schema{Type: "array"}
+	case strings.Contains(t, "json"):
+		return &jsonschema.Schema{}
+	case strings.Contains(t, "bool") || t == "bit":
+		return &jsonschema.Schema{Type: "boolean"}
+	case strings.Contains(t, "int") || t == "serial" || t == "bigserial":
+		return &jsonschema.Schema{Type: "integer"}
+	case strings.Contains(t, "numeric") || strings.Contains(t, "decimal") || strings.Contains(t, "real") ||
+		strings.Contains(t, "double") || strings.Contains(t, "float") || strings.Contains(t, "money"):
+		return &jsonschema.Schema{Type: "number"}
+	case strings.HasPrefix(t, "timestamp") || t == "datetime" || t == "datetime2" || t == "datetimeoffset":
+		return &jsonschema.Schema{Type: "string", Format: "date-time"}
+	case t == "date":
+		return &jsonschema.Schema{Type: "string", Format: "date"}
+	case strings.HasPrefix(t, "time"):
+		return &jsonschema.Schema{Type: "string", Format: "time"}
+	case t == "uuid" || t == "uniqueidentifier":
+		return &jsonschema.Schema{Type: "string", Format: "uuid"}
+	}
+	return &jsonschema.Schema{Type: "string"}
+}
+
+// objectSchema Схема объекта со свойствами в заданном порядке
+func objectSchema(description string, properties [][2]any) *jsonschema.Schema {
+	s := &jsonschema.Schema{Type: "object", Description: description, Properties: orderedmap.New()}
+	for _, p := range properties {
+		s.Properties.Set(p[0].(string), p[1])
+	}
+	return s
+}
+
+func arraySchema(items *jsonschema.Schema, description string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "array", Items: items, Description: description}
+}
+
+func schemaRef(name string) *jsonschema.Schema {
+	return &jsonschema.Schema{Ref: "#/components/schemas/" + name}
+}
+
+func parameterRef(name string) *OpenAPIParameter {
+	return &OpenAPIParameter{Ref: "#/components/parameters/" + name}
+}
+
+func headerRef(name string) *OpenAPIHeader {
+	return &OpenAPIHeader{Ref: "#/components/headers/" + name}
+}
+
+func responseRef(name string) *OpenAPIResponse {
+	return &OpenAPIResponse{Ref: "#/components/responses/" + name}
+}
+
+// recordsBody Тело с записью или массивом записей при X-Content-Type: array
+func recordsBody(record, records *jsonschema.Schema) *OpenAPIRequestBody {
+	schema := &jsonschema.Schema{OneOf: []*jsonschema.Schema{record, records}}
+	return &OpenAPIRequestBody{
+		Required: true,
+		Content: map[string]*OpenAPIMediaType{
+			consts.MIMEApplicationJSON: {Schema: schema},
+			consts.MIMEApplicationXML:  {Schema: schema},
+		},
+	}
+}
+
+// envelopeResponses Ответы операций изменения записей с кодом успешного ответа status
+func envelopeResponses(status string) map[string]*OpenAPIResponse {
+	return map[string]*OpenAPIResponse{
+		status: {
+			Description: "Результат",
+			Content: map[string]*OpenAPIMediaType{
+				consts.MIMEApplicationJSON: {Schema: schemaRef("Envelope")},
+				consts.MIMEApplicationXML:  {Schema: schemaRef("Envelope")},
+			},
+		},
+		"default": responseRef("Problem"),
+	}
+}
@@ -0,0 +1,65 @@
+package crud
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ewa-go/jsonschema"
+)
+
+func TestOpenAPI_AddResource(t *testing.T) {
+	_, r := newFakeSQL()
+	r.SetTableTypeTable("public.users", true).SetTableTypeView("public.users_view")
+	doc := NewOpenAPI("Users", "1.0.0")
+	if err := doc.AddResource("/users", r); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, len(doc.Paths), 2)
+
+	collection := doc.Paths["/users"]
+	assertEq(t, collection.Get.OperationID, "list_public.users")
+	assertEq(t, strings.Contains(collection.Get.Description, "!array"), true)
+	assertEq(t, collection.Get.Parameters[0].Ref, "#/components/parameters/Filter")
+	assertEq(t, collection.Get.Parameters[6].Name, HeaderTableType)
+	assertEq(t, collection.Get.Parameters[6].Schema.Default, "table")
+	assertEq(t, collection.Get.Responses["200"].Headers[HeaderTotal].Ref, "#/components/headers/Total")
+	assertEq(t, collection.Get.Responses["304"].Ref, "#/components/responses/NotModified")
+	assertEq(t, collection.Post.Responses["201"].Content["application/json"].Schema.Ref, "#/components/schemas/Envelope")
+	assertEq(t, collection.Put.Responses["200"].Content["application/json"].Schema.Ref, "#/components/schemas/Envelope")
+
+	item := doc.Paths["/users/{id}"]
+	assertEq(t, item.Parameters[0].In, "path")
+	assertEq(t, item.Get.Responses["200"].Content["application/json"].Schema.Ref, "#/components/schemas/public.users")
+
+	// Схема записи из описания столбцов обработчика
+	b, err := json.Marshal(doc.Components.Schemas["public.users"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, string(b), `{"properties":{"id":{"type":"integer"},"name":{"type":"string","description":"User name"},"data":true},"type":"object"}`)
+
+	// Без имени модели
+	if err = doc.AddResource("/empty", getCRUD()); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestOpenAPI_Schema(t *testing.T) {
+	doc := NewOpenAPI("Users", "1.0.0")
+	r := getCRUD().SetModelName("users").SetSchema(jsonschema.Reflect(&schemaUser{}))
+	if err := doc.AddResource("/users", r); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, doc.Components.Schemas["users"], r.Schema)
+	_, ok := doc.Paths["/users/{id}"]
+	assertEq(t, ok, false)
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, strings.HasPrefix(string(b), `{"openapi":"3.1.0","info":{"title":"Users","version":"1.0.0"}`), true)
+	assertEq(t, columnSchema("timestamp with time zone").Format, "date-time")
+	assertEq(t, columnSchema("numeric(10,2)").Type, "number")
+}
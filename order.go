@@ -0,0 +1,97 @@
+package crud
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Order Сортировка по полю
+type Order struct {
+	Field string
+	Desc  bool
+	// Nulls Положение null значений: FIRST, LAST или по-умолчанию
+	Nulls string
+}
+
+var identRegexp = regexp.MustCompile(`^\w+$`)
+
+// ParseOrders Разбор сортировок вида "field [asc|desc] [nulls first|last]".
+// Если переданы столбцы, то поле сортировки должно быть среди них
+func ParseOrders(orders []string, columns []string) ([]Order, error) {
+	result := make([]Order, 0, len(orders))
+	for _, order := range orders {
+		a := strings.Fields(order)
+		if len(a) == 0 {
+			return nil, fmt.Errorf("empty order")
+		}
+		o := Order{Field: a[0]}
+		if !isColumn(o.Field, columns) {
+			return nil, fmt.Errorf("unknown order field %s", o.Field)
+		}
+		a = a[1:]
+		if len(a) > 0 {
+			switch strings.ToLower(a[0]) {
+			case "asc":
+				a = a[1:]
+			case "desc":
+				o.Desc = true
+				a = a[1:]
+			}
+		}
+		if len(a) > 0 {
+			if len(a) != 2 || strings.ToLower(a[0]) != "nulls" {
+				return nil, fmt.Errorf("invalid order %s", order)
+			}
+			switch o.Nulls = strings.ToUpper(a[1]); o.Nulls {
+			case "FIRST", "LAST":
+			default:
+				return nil, fmt.Errorf("invalid order %s", order)
+			}
+		}
+		result = append(result, o)
+	}
+	return result, nil
+}
+
+// isColumn Проверка поля по списку столбцов. Без списка допускаются только простые идентификаторы
+func isColumn(field string, columns []string) bool {
+	if len(columns) == 0 {
+		return identRegexp.MatchString(field)
+	}
+	for _, column := range columns {
+		if column == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Direction Направление сортировки ASC или DESC
+func (o Order) Direction() string {
+	if o.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// String Сортировка в виде "field ASC|DESC [NULLS FIRST|LAST]"
+func (o Order) String() string {
+	s := o.Field + " " + o.Direction()
+	if len(o.Nulls) > 0 {
+		s += " NULLS " + o.Nulls
+	}
+	return s
+}
+
+// reverse Обратная сортировка
+func (o Order) reverse() Order {
+	o.Desc = !o.Desc
+	switch o.Nulls {
+	case "FIRST":
+		o.Nulls = "LAST"
+	case "LAST":
+		o.Nulls = "FIRST"
+	}
+	return o
+}
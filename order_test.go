@@ -0,0 +1,54 @@
+package crud
+
+import "testing"
+
+func TestParseOrders(t *testing.T) {
+	orders, err := ParseOrders([]string{"name", "id DESC", "age asc nulls first", "created_at nulls LAST"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, len(orders), 4)
+	assertEq(t, orders[0], Order{Field: "name"})
+	assertEq(t, orders[1], Order{Field: "id", Desc: true})
+	assertEq(t, orders[2], Order{Field: "age", Nulls: "FIRST"})
+	assertEq(t, orders[3], Order{Field: "created_at", Nulls: "LAST"})
+
+	for _, order := range []string{"", "name; drop table users", "name up", "name desc nulls", "name nulls middle", `"name"`} {
+		if _, err = ParseOrders([]string{order}, nil); err == nil {
+			t.Fatalf("expected error for %q", order)
+		}
+	}
+	if _, err = ParseOrders([]string{"age"}, []string{"id", "name"}); err == nil {
+		t.Fatal("expected unknown order field error")
+	}
+}
+
+func TestPaging(t *testing.T) {
+	f := &Filter{Orders: []string{"name desc nulls last", "id"}, Limit: 10, Offset: 20}
+
+	paging, err := new(PostgresFormat).Paging(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, paging, `ORDER BY "name" DESC NULLS LAST, "id" ASC LIMIT 10 OFFSET 20`)
+
+	paging, err = new(MySQLFormat).Paging(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, paging, "ORDER BY `name` IS NULL ASC, `name` DESC, `id` ASC LIMIT 10 OFFSET 20")
+
+	paging, err = new(SQLiteFormat).Paging(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, paging, `ORDER BY "name" DESC NULLS LAST, "id" ASC LIMIT 10 OFFSET 20`)
+
+	// OFFSET без LIMIT
+	paging, _ = new(MySQLFormat).Paging(&Filter{Offset: 5}, nil)
+	assertEq(t, paging, "LIMIT 18446744073709551615 OFFSET 5")
+	paging, _ = new(SQLiteFormat).Paging(&Filter{Offset: 5}, nil)
+	assertEq(t, paging, "LIMIT -1 OFFSET 5")
+	paging, _ = new(PostgresFormat).Paging(&Filter{Offset: 5}, nil)
+	assertEq(t, paging, "OFFSET 5")
+}
@@ -0,0 +1,134 @@
+package crud
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Placeholder Стиль плейсхолдеров в sql запросе
+type Placeholder string
+
+const (
+	PlaceholderQuestion Placeholder = "?"
+	PlaceholderDollar   Placeholder = "$"
+	PlaceholderAtP      Placeholder = "@p"
+	PlaceholderColon    Placeholder = ":"
+)
+
+// IPlaceholder Необязательный интерфейс для IQueryParam и IHandlers со стилем плейсхолдеров по-умолчанию
+type IPlaceholder interface {
+	DefaultPlaceholder() Placeholder
+}
+
+// IExpandArrays Необязательный интерфейс для IQueryParam, драйверы которого не передают массивы одним значением.
+// Если ExpandArrays возвращает true, то массивы раскрываются поэлементно при любом стиле плейсхолдеров
+type IExpandArrays interface {
+	ExpandArrays() bool
+}
+
+// Bind Замена плейсхолдеров ? на плейсхолдеры стиля p.
+// Диапазон between ? and ? всегда занимает два плейсхолдера.
+// Массивы при expand раскрываются поэлементно, иначе передаются одним значением:
+// для $n оборачиваются в pq.Array, а in(?) заменяется на = any(?).
+// Стили @p и : не поддерживают массивы, поэтому раскрывают их всегда.
+// ? внутри строк и идентификаторов в одинарных, двойных и обратных кавычках не заменяются
+func (p Placeholder) Bind(query string, args []any, expand bool) (string, []any) {
+	if p == "" {
+		p = PlaceholderQuestion
+	}
+	if p == PlaceholderAtP || p == PlaceholderColon {
+		expand = true
+	}
+	var (
+		b      strings.Builder
+		values []any
+		n      int
+		quote  byte
+	)
+	add := func(value any) {
+		values = append(values, value)
+		switch p {
+		case PlaceholderQuestion:
+			b.WriteByte('?')
+		case PlaceholderColon:
+			name := "p" + strconv.Itoa(len(values))
+			values[len(values)-1] = sql.Named(name, value)
+			b.WriteString(":" + name)
+		default:
+			b.WriteString(string(p) + strconv.Itoa(len(values)))
+		}
+	}
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case quote == 0 && (c == '\'' || c == '"' || c == '`'):
+			quote = c
+		case quote == c:
+			quote = 0
+		}
+		if quote != 0 || query[i] != '?' || n >= len(args) {
+			b.WriteByte(query[i])
+			continue
+		}
+		arg := args[n]
+		n++
+		items, ok := sliceOf(arg)
+		if !ok {
+			add(arg)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(query[i+1:], " and ?") && len(items) == 2:
+			add(items[0])
+			b.WriteString(" and ")
+			add(items[1])
+			i += len(" and ?")
+		case expand:
+			for j, item := range items {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				add(item)
+			}
+		case p == PlaceholderDollar:
+			s := b.String()
+			switch {
+			case strings.HasSuffix(s, "not in("):
+				s = strings.TrimSuffix(s, "not in(") + "<> all("
+			case strings.HasSuffix(s, "in("):
+				s = strings.TrimSuffix(s, "in(") + "= any("
+			case strings.HasSuffix(s, "ARRAY[") && strings.HasPrefix(query[i+1:], "]"):
+				s = strings.TrimSuffix(s, "ARRAY[")
+				i++
+			}
+			b.Reset()
+			b.WriteString(s)
+			add(pq.Array(arg))
+		default:
+			add(arg)
+		}
+	}
+	return b.String(), values
+}
+
+// sliceOf Разложить срез на элементы, []byte срезом не считается
+func sliceOf(value any) ([]any, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if _, ok := value.([]byte); ok {
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return nil, false
+	}
+	items := make([]any, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items, true
+}
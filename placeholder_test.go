@@ -0,0 +1,89 @@
+package crud
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestPlaceholder_Bind(t *testing.T) {
+	r := getCRUD()
+	q := &QueryParams{}
+	q.ID = QueryFormat(r, "id", "1::int")
+	q.Set("name", QueryFormat(r, "name", "[a,b]"))
+	where, args := r.IQueryParam.Query(q, r.Columns(r))
+	assertEq(t, where, `"id" = ? and "name" in(?)`)
+
+	query, values := PlaceholderQuestion.Bind(where, args, false)
+	assertEq(t, query, `"id" = ? and "name" in(?)`)
+	assertArrayEq(t, []any{1, []string{"a", "b"}}, values)
+
+	query, values = PlaceholderQuestion.Bind(where, args, true)
+	assertEq(t, query, `"id" = ? and "name" in(?,?)`)
+	assertArrayEq(t, []any{1, "a", "b"}, values)
+
+	query, values = PlaceholderDollar.Bind(where, args, false)
+	assertEq(t, query, `"id" = $1 and "name" = any($2)`)
+	assertEq(t, len(values), 2)
+	if _, ok := values[1].(*pq.StringArray); !ok {
+		t.Fatalf("expected pq.StringArray, got %T", values[1])
+	}
+
+	query, _ = PlaceholderDollar.Bind(where, args, true)
+	assertEq(t, query, `"id" = $1 and "name" in($2,$3)`)
+
+	query, _ = PlaceholderAtP.Bind(where, args, false)
+	assertEq(t, query, `"id" = @p1 and "name" in(@p2,@p3)`)
+
+	query, values = PlaceholderColon.Bind(where, args, false)
+	assertEq(t, query, `"id" = :p1 and "name" in(:p2,:p3)`)
+	assertEq(t, values[2], sql.Named("p3", "b"))
+}
+
+func TestPlaceholder_BindRange(t *testing.T) {
+	// Диапазон всегда занимает два значения
+	query, values := PlaceholderQuestion.Bind(`"age" between ? and ?`, []any{[]int{1, 5}}, false)
+	assertEq(t, query, `"age" between ? and ?`)
+	assertArrayEq(t, []any{1, 5}, values)
+
+	r := getMySQLCRUD()
+	assertEq(t, r.GetPlaceholder(), PlaceholderQuestion)
+	query, values = r.Bind("`name` in(?)", []any{[]string{"a", "b"}})
+	assertEq(t, query, "`name` in(?,?)")
+	assertArrayEq(t, []any{"a", "b"}, values)
+}
+
+func TestPlaceholder_BindQuotes(t *testing.T) {
+	query, values := PlaceholderDollar.Bind(`"a?" ->> 'b?''c' = ? and `+"`d?`"+` = ?`, []any{1, 2}, false)
+	assertEq(t, query, `"a?" ->> 'b?''c' = $1 and `+"`d?`"+` = $2`)
+	assertArrayEq(t, []any{1, 2}, values)
+}
+
+func TestCRUD_GetPlaceholder(t *testing.T) {
+	r := New(NewSQLHandlers(nil))
+	assertEq(t, r.GetPlaceholder(), PlaceholderDollar)
+	assertEq(t, r.SetIQueryParam(new(MySQLFormat)).GetPlaceholder(), PlaceholderQuestion)
+	assertEq(t, r.SetIQueryParam(new(SQLServerFormat)).GetPlaceholder(), PlaceholderAtP)
+	assertEq(t, r.SetPlaceholder(PlaceholderColon).GetPlaceholder(), PlaceholderColon)
+}
+
+func TestPlaceholder_BindArray(t *testing.T) {
+	r := getCRUD().SetPlaceholder(PlaceholderDollar)
+	q := &QueryParams{}
+	q.ID = QueryFormat(r, "id[:]", "[1|5]::int")
+	q.Set("group_ids", QueryFormat(r, "group_ids[!&&]", "[1,2]::int"))
+	query, values := r.Query(q, r.Columns(r))
+	assertEq(t, query, `"id" between $1 and $2 and not "group_ids" && $3::int[]`)
+	assertEq(t, len(values), 3)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "[1,2]::int")
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, `"id" = any($1)`)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id[!]", "[1,2]::int")
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, `"id" <> all($1)`)
+}
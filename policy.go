@@ -0,0 +1,147 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+// PolicyRule Условия политики для пользователя: ключ key[znak] как в адресной строке и значение без разбора,
+// массив значений - условие in. Ошибка запрещает доступ
+type PolicyRule func(c *ewa.Context, i *security.Identity) (Map, error)
+
+// Policy Политика доступа к строкам по пользователю
+type Policy struct {
+	// Actions Действия, к которым применяется политика: Read, Created, Updated, Deleted, Restored. Если не заданы, то ко всем
+	Actions []string
+	// Rule Условия политики
+	Rule PolicyRule
+	// Overwrite Поля условий на равенство в теле заменяются значениями политики, иначе при несовпадении возвращается 403
+	Overwrite bool
+}
+
+// SetPolicy Добавление политики доступа к строкам для действий actions, без действий - для всех.
+// Условия соединяются через AND с условиями запроса и не могут быть переопределены клиентом
+func (r *CRUD) SetPolicy(rule PolicyRule, overwrite bool, actions ...string) *CRUD {
+	r.Policies = append(r.Policies, Policy{Actions: actions, Rule: rule, Overwrite: overwrite})
+	return r
+}
+
+// OwnerPolicy Условие на владельца записи: поле field равно переменной пользователя variable
+func OwnerPolicy(field, variable string) PolicyRule {
+	return func(c *ewa.Context, i *security.Identity) (Map, error) {
+		if i == nil || i.GetVariable(variable) == nil {
+			return nil, Forbidden("%s is not defined for user", variable)
+		}
+		return Map{field: i.GetVariable(variable)}, nil
+	}
+}
+
+// applies Проверка, что политика применяется к действию
+func (p Policy) applies(action string) bool {
+	if len(p.Actions) == 0 {
+		return true
+	}
+	for _, a := range p.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPolicies Добавление условий политик действия к параметрам запроса params и проверка полей тела body.
+// Поля тела проверяются только по условиям на равенство, при создании отсутствующие поля заполняются
+func (r *CRUD) applyPolicies(c *ewa.Context, action string, params *QueryParams, body *Body) (int, error) {
+	for _, p := range r.Policies {
+		if p.Rule == nil || !p.applies(action) {
+			continue
+		}
+		conditions, err := p.Rule(c, c.Identity)
+		if err != nil {
+			return consts.StatusForbidden, err
+		}
+		for _, key := range sortedKeys(conditions) {
+			value := conditions[key]
+			param, err := r.policyParam(key, value)
+			if err != nil {
+				return consts.StatusInternalServerError, fmt.Errorf("policy %s: %w", key, err)
+			}
+			if params != nil {
+				params.And(param)
+			}
+			if body != nil && param.Key == strings.TrimSpace(key) {
+				if err = p.body(body, key, value, action == Created); err != nil {
+					return consts.StatusForbidden, err
+				}
+			}
+		}
+	}
+	return consts.StatusOK, nil
+}
+
+// body Проверка или замена значения поля в записях тела, fill - заполнение отсутствующего поля
+func (p Policy) body(body *Body, field string, value any, fill bool) error {
+	records := []map[string]interface{}{body.Data}
+	if body.IsArray {
+		records = body.Array
+	}
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		old, ok := record[field]
+		switch {
+		case !ok && fill, ok && p.Overwrite:
+			record[field] = value
+		case ok && !policyEqual(old, value):
+			return Forbidden("%s value is not allowed", field)
+		}
+	}
+	return nil
+}
+
+// policyParam Условие политики: оператор берётся из ключа key[znak], а значение передаётся как есть,
+// поэтому строки вроде null или [a,b] не разбираются как в адресной строке
+func (r *CRUD) policyParam(key string, value any) (*QueryParam, error) {
+	q := &QueryParam{
+		Key:      strings.TrimSpace(key),
+		Znak:     "=",
+		Value:    value,
+		IsQuotes: true,
+		Type:     ValueType,
+	}
+	r.parseZnak(q)
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		q.Type = ArrayType
+	}
+	return r.Format(r, q)
+}
+
+// policyEqual Сравнение значения поля тела со значением политики. Простые значения сравниваются в строковом виде,
+// как их сравнит бд с приведением типа, поэтому строка "5" и число 5 из json совпадают
+func policyEqual(a, b any) bool {
+	if equalValues(a, b) {
+		return true
+	}
+	if a == nil || b == nil || !isScalar(a) || !isScalar(b) {
+		return false
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// isScalar Строка, число или логическое значение
+func isScalar(value any) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+func TestReadHandler_Policy(t *testing.T) {
+	d, r := newFakeSQL()
+	r.SetPolicy(OwnerPolicy("owner_id", "id"), false)
+
+	identity := new(security.Identity).SetVariable("id", int64(7))
+	tc := newTestContext().AddQuery("name", "a").AddQuery("[|]name", "b")
+	if err := r.ReadHandler(&ewa.Context{IContext: tc, Identity: identity}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, d.queries[1], `SELECT * FROM "public"."users" WHERE ("name" = $1 or "name" = $2) and "owner_id" = $3`)
+	assertArrayStringEq(t, d.args[1], []any{"a", "b", "7"})
+
+	// Без пользователя доступ запрещён
+	tc = newTestContext()
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, consts.StatusForbidden)
+
+	// Политика только для изменения не применяется к чтению
+	r.Policies = nil
+	r.SetPolicy(OwnerPolicy("owner_id", "id"), false, Updated, Deleted)
+	tc = newTestContext().SetParam("id", "1")
+	if err := r.DeleteHandler(&ewa.Context{IContext: tc, Identity: identity}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[len(d.queries)-1], `DELETE FROM "public"."users" WHERE "id" = $1 and "owner_id" = $2`)
+	tc = newTestContext().SetParam("id", "1")
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+}
+
+func TestCreateHandler_Policy(t *testing.T) {
+	identity := new(security.Identity).SetVariable("id", "7")
+	create := func(r *CRUD, body string) (*testContext, *Body) {
+		var data *Body
+		before := func(c *ewa.Context, r *CRUD, i *security.Identity, q *QueryParams, body *Body) (int, error) {
+			data = body
+			return consts.StatusOK, nil
+		}
+		tc := newTestContext().SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).SetBody(body)
+		if err := r.CreateHandler(&ewa.Context{IContext: tc, Identity: identity}, before, nil); err != nil {
+			t.Fatal(err)
+		}
+		return tc, data
+	}
+	r := getCRUD().SetPolicy(OwnerPolicy("owner_id", "id"), false)
+	_, body := create(r, `{"name":"Name"}`)
+	assertEq(t, body.GetField("owner_id"), "7")
+	// Число 7 из json совпадает со строкой "7" пользователя
+	tc, _ := create(r, `{"name":"Name","owner_id":7}`)
+	assertEq(t, tc.response.status, 200)
+	tc, _ = create(r, `{"name":"Name","owner_id":8}`)
+	assertEq(t, tc.response.status, consts.StatusForbidden)
+
+	r = getCRUD().SetPolicy(OwnerPolicy("owner_id", "id"), true)
+	_, body = create(r, `{"name":"Name","owner_id":8}`)
+	assertEq(t, body.GetField("owner_id"), "7")
+}
+
+func TestPolicyParam(t *testing.T) {
+	r := getCRUD()
+	// Строки не разбираются как в адресной строке
+	q, err := r.policyParam("name", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, q.Znak, "= ?")
+	assertEq(t, q.Value, "null")
+	q, _ = r.policyParam("name", "[a,b]")
+	assertEq(t, q.Value, "[a,b]")
+	assertEq(t, q.IsArray(), false)
+
+	q, _ = r.policyParam("level[<-]", 3)
+	assertEq(t, q.Key, "level")
+	assertEq(t, q.Znak, "<= ?")
+	assertEq(t, q.Value, 3)
+	q, _ = r.policyParam("owner_id", []int64{1, 2})
+	assertEq(t, q.IsArray(), true)
+	q, _ = r.policyParam("owner_id", nil)
+	assertEq(t, q.Znak, "is null")
+
+	assertEq(t, policyEqual(float64(5), "5"), true)
+	assertEq(t, policyEqual("5", int64(5)), true)
+	assertEq(t, policyEqual(float64(6), "5"), false)
+	assertEq(t, policyEqual(nil, "null"), false)
+}
+
+func TestPolicy_IDWithOr(t *testing.T) {
+	d, r := newFakeSQL()
+	r.SetPolicy(OwnerPolicy("owner_id", "id"), false)
+	identity := new(security.Identity).SetVariable("id", int64(7))
+
+	// Условие [|] рядом с ID не обходит политику
+	tc := newTestContext().SetParam("id", "1").AddQuery("[|]name", "x")
+	if err := r.ReadHandler(&ewa.Context{IContext: tc, Identity: identity}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[len(d.queries)-1], `SELECT * FROM "public"."users" WHERE ("id" = $1 or "name" = $2) and "owner_id" = $3 LIMIT 1`)
+
+	tc = newTestContext().
+		SetParam("id", "1").
+		AddQuery("[|]name", "x").
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"name":"Name2"}`)
+	if err := r.UpdateHandler(&ewa.Context{IContext: tc, Identity: identity}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[len(d.queries)-1], `UPDATE "public"."users" SET "name" = $1 WHERE ("id" = $2 or "name" = $3) and "owner_id" = $4`)
+
+	tc = newTestContext().SetParam("id", "1").AddQuery("[|]name", "x")
+	if err := r.DeleteHandler(&ewa.Context{IContext: tc, Identity: identity}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[len(d.queries)-1], `DELETE FROM "public"."users" WHERE ("id" = $1 or "name" = $2) and "owner_id" = $3`)
+	assertArrayStringEq(t, d.args[len(d.args)-1], []any{int64(1), "x", int64(7)})
+}
@@ -14,14 +14,25 @@ type QueryParam struct {
 	DataType string
 	IsQuotes bool
 	IsOR     bool
+	IsNot    bool
+	Group    string
 }
 
 type QueryParams struct {
 	Filter *Filter
 	ID     *QueryParam
+	// Count Способ подсчёта Total, запрошенный клиентом. Обработчик может заменить его на фактически применённый
+	Count Count
+	// Counted Способ, которым обработчик подсчитал Total. Пусто, если обработчик о подсчёте не сообщил
+	Counted Count
+	// Changes Изменения по полям при SetTrackChanges, заполняются перед изменением и удалением записей
+	Changes []RecordChange
 
 	m      map[string][]*QueryParam
 	values []*QueryParam
+	tree   *Expr
+	// and Условия, которые соединяются через AND со всем условием клиента
+	and []*QueryParam
 }
 
 type Filter struct {
@@ -29,7 +40,12 @@ type Filter struct {
 	Orders []string               `json:"orders,omitempty"`
 	Limit  int                    `json:"limit,omitempty"`
 	Offset int                    `json:"offset,omitempty"`
+	Cursor string                 `json:"cursor,omitempty"`
 	Vars   map[string]interface{} `json:"vars,omitempty"`
+
+	cursor *Cursor
+	// keys Столбцы сортировки вне Fields, которые выбираются дополнительно для курсоров
+	keys []string
 }
 
 type Map map[string]interface{}
@@ -44,6 +60,9 @@ const (
 	RangeType Type = "range"
 )
 
+// prefixRegexp Префикс ключа [|!group]
+var prefixRegexp = regexp.MustCompile(`^\[(\|)?(!)?([\w.]*)]`)
+
 type Range struct {
 	From string
 	To   string
@@ -91,7 +110,58 @@ func (q *QueryParams) Set(key string, param *QueryParam) {
 	if param != nil {
 		q.m[key] = append(q.m[key], param)
 		q.values = append(q.values, param)
+		if key == AllFieldsParamName || key == ExtraParamName {
+			return
+		}
+		if q.tree == nil {
+			q.tree = new(Expr)
+		}
+		q.tree.Add(param)
+	}
+}
+
+// And Добавление условия, которое соединяется через AND со всем условием клиента вместе с ID:
+// (ID и параметры в порядке передачи) and param. Соединения внутри условия клиента сохраняются
+func (q *QueryParams) And(param *QueryParam) {
+	if q.m == nil {
+		q.m = make(map[string][]*QueryParam)
+	}
+	param.IsOR = false
+	q.m[param.Key] = append(q.m[param.Key], param)
+	q.values = append(q.values, param)
+	q.and = append(q.and, param)
+}
+
+// Tree Вернуть дерево условий в порядке передачи параметров
+func (q *QueryParams) Tree() *Expr {
+	if q.tree == nil {
+		q.tree = new(Expr)
+	}
+	return q.tree
+}
+
+// Where Формирование условия по дереву: сначала ID, затем параметры в порядке их передачи,
+// условия из And добавляются к ним через AND. cond возвращает условие для одного параметра
+func (q *QueryParams) Where(cond func(param *QueryParam) string) (string, []any) {
+	client := new(Expr)
+	if q.ID != nil {
+		client.Children = append(client.Children, &Expr{Param: q.ID})
+	}
+	client.Children = append(client.Children, q.Tree().Children...)
+	if len(q.and) == 0 {
+		return client.Render(cond)
+	}
+	root := new(Expr)
+	switch {
+	case len(client.Children) == 1:
+		root.Children = append(root.Children, client.Children[0])
+	case len(client.Children) > 0:
+		root.Children = append(root.Children, client)
+	}
+	for _, param := range q.and {
+		root.Children = append(root.Children, &Expr{Param: param})
 	}
+	return root.Render(cond)
 }
 
 // Get Вернуть карту параметров
@@ -125,18 +195,14 @@ func (r *CRUD) QueryFormat(key, value string) (q *QueryParam, err error) {
 		Type:     ValueType,
 		DataType: "",
 	}
-	if len(q.Key) > 3 && q.Key[:3] == "[|]" {
-		q.IsOR = true
-		q.Key = q.Key[3:]
-	}
-	rgx := regexp.MustCompile(r.Pattern())
-	if rgx.MatchString(q.Key) {
-		matches := rgx.FindStringSubmatch(q.Key)
-		if len(matches) == 2 {
-			q.Znak = matches[1]
-			q.Key = rgx.ReplaceAllString(q.Key, "")
-		}
+	// Префикс [|!group] - соединение через OR, отрицание и группа условия
+	if matches := prefixRegexp.FindStringSubmatch(q.Key); matches != nil {
+		q.IsOR = len(matches[1]) > 0
+		q.IsNot = len(matches[2]) > 0
+		q.Group = matches[3]
+		q.Key = q.Key[len(matches[0]):]
 	}
+	r.parseZnak(q)
 	index := strings.Index(value, "::")
 	if index > -1 {
 		q.DataType = value[index+2:]
@@ -148,6 +214,18 @@ func (r *CRUD) QueryFormat(key, value string) (q *QueryParam, err error) {
 	return r.Format(r, q)
 }
 
+// parseZnak Оператор из ключа вида key[znak] по шаблону диалекта
+func (r *CRUD) parseZnak(q *QueryParam) {
+	rgx := regexp.MustCompile(r.Pattern())
+	if rgx.MatchString(q.Key) {
+		matches := rgx.FindStringSubmatch(q.Key)
+		if len(matches) == 2 {
+			q.Znak = matches[1]
+			q.Key = rgx.ReplaceAllString(q.Key, "")
+		}
+	}
+}
+
 func (q *QueryParam) IsValue() bool {
 	return q.Type == ValueType
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/ewa-go/ewa"
 )
 
 func assertEq(t *testing.T, a interface{}, b interface{}) {
@@ -78,7 +80,7 @@ func TestParams(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assertArrayEq(t, []any{4, []time.Time{from, to}}, values)
+	assertArrayEq(t, []any{4, from, to}, values)
 
 	q = &QueryParams{}
 	q.ID = QueryFormat(r, "id", "5::int")
@@ -308,3 +310,70 @@ func TestCast(t *testing.T) {
 	}
 	assertArrayStringEq(t, q.Value, []int{1, 10})
 }
+
+func TestOrder(t *testing.T) {
+	r := getCRUD()
+	c := ewa.NewContext(newTestContext().
+		AddQuery("c", "3::int").
+		AddQuery("a", "1::int").
+		AddQuery("[|]b", "2::int").
+		AddQuery("a[!]", "4::int"))
+	for i := 0; i < 100; i++ {
+		q, err := r.NewQueryParams(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query, values := r.Query(q, r.Columns(r))
+		assertEq(t, query, `"c" = ? and "a" = ? or "b" = ? and "a" != ?`)
+		assertArrayEq(t, []any{3, 1, 2, 4}, values)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	r := getCRUD()
+	// (a or b) and not (c or d)
+	c := ewa.NewContext(newTestContext().
+		AddQuery("[g1]a", "1::int").
+		AddQuery("[|g1]b", "2::int").
+		AddQuery("[!g2]c", "3::int").
+		AddQuery("[|g2]d", "4::int"))
+	q, err := r.NewQueryParams(c, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, values := r.Query(q, r.Columns(r))
+	assertEq(t, query, `("a" = ? or "b" = ?) and not ("c" = ? or "d" = ?)`)
+	assertArrayEq(t, []any{1, 2, 3, 4}, values)
+
+	// id = ? and (a or (b and c)) or not e
+	c = ewa.NewContext(newTestContext().
+		SetParam("id", "10::int").
+		AddQuery("[g1]a", "1::int").
+		AddQuery("[|g1.g2]b", "2::int").
+		AddQuery("[g1.g2]c[>]", "3::int").
+		AddQuery("[|!]e", "null"))
+	q, err = r.SetFieldIdName("id").NewQueryParams(c, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, `"id" = ? and ("a" = ? or ("b" = ? and "c" > ?)) or not ("e" is null)`)
+	assertArrayEq(t, []any{10, 1, 2, 3}, values)
+
+	// Обход дерева обработчиком
+	var groups []string
+	q.Tree().Walk(func(e *Expr) bool {
+		if e.IsGroup() && len(e.Group) > 0 {
+			groups = append(groups, e.Group)
+		}
+		return true
+	})
+	assertArrayStringEq(t, groups, []string{"g1", "g1.g2"})
+
+	r = getMySQLCRUD()
+	q = &QueryParams{}
+	q.Set("a", QueryFormat(r, "[g1]a", "1"))
+	q.Set("b", QueryFormat(r, "[|g1]b[~]", "x"))
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, "(`a` = ? or CONVERT(`b` USING utf8mb4) COLLATE utf8mb4_bin REGEXP ?)")
+}
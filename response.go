@@ -3,10 +3,12 @@ package crud
 import (
 	"encoding/json"
 	"encoding/xml"
-	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/ewa-go/ewa"
 	"github.com/ewa-go/ewa/consts"
-	"time"
 )
 
 type Response struct {
@@ -14,42 +16,205 @@ type Response struct {
 	State    string    `json:"state"`
 	Datetime time.Time `json:"datetime"`
 	Data     any       `json:"data,omitempty"`
+
+	encoders []encoder
+}
+
+// Encoder Кодирование данных ответа
+type Encoder func(data any) ([]byte, error)
+
+type encoder struct {
+	mediaType   string
+	contentType string
+	encode      Encoder
+}
+
+// defaultEncoders Кодировщики по-умолчанию, первый используется для Accept: */* и без заголовка
+var defaultEncoders = []encoder{
+	{consts.MIMEApplicationJSON, consts.MIMEApplicationJSONCharsetUTF8, json.Marshal},
+	{consts.MIMEApplicationXML, consts.MIMEApplicationXMLCharsetUTF8, xml.Marshal},
+	{consts.MIMETextXML, consts.MIMETextXMLCharsetUTF8, xml.Marshal},
+}
+
+// NewResponse Инициализация ответа с кодировщиками по-умолчанию
+func NewResponse() *Response {
+	return new(Response)
+}
+
+// SetEncoder Регистрация кодировщика для типа содержимого, например "text/csv; charset=utf-8".
+// Кодировщик того же типа заменяется
+func (r *Response) SetEncoder(contentType string, encode Encoder) *Response {
+	if r.encoders == nil {
+		r.encoders = append([]encoder{}, defaultEncoders...)
+	}
+	e := encoder{mediaType: mediaTypeOf(contentType), contentType: contentType, encode: encode}
+	for i := range r.encoders {
+		if r.encoders[i].mediaType == e.mediaType {
+			r.encoders[i] = e
+			return r
+		}
+	}
+	r.encoders = append(r.encoders, e)
+	return r
 }
 
 func (r Response) Send(c *ewa.Context, state string, status int, data any) (err error) {
-	var (
-		body        any
-		contentType string
-		content     []byte
-	)
-	switch t := data.(type) {
-	case error:
-		r.Ok = false
-		body = t.Error()
-	default:
-		r.Ok = true
-		body = data
+	if e, ok := data.(error); ok {
+		return r.problem(c, status, e)
 	}
+	e, ok := r.negotiate(c.Get(consts.HeaderAccept))
+	if !ok {
+		return r.problem(c, consts.StatusNotAcceptable, NotAcceptable("supported: %s", strings.Join(r.mediaTypes(), ", ")))
+	}
+	content, err := r.encode(e, state, data)
+	if err != nil {
+		return r.problem(c, consts.StatusInternalServerError, err)
+	}
+	return c.Send(status, e.contentType, content)
+}
+
+// Encode Кодирование ответа кодировщиком по заголовку Accept без отправки
+func (r Response) Encode(c *ewa.Context, state string, data any) (string, []byte, error) {
+	e, ok := r.negotiate(c.Get(consts.HeaderAccept))
+	if !ok {
+		return "", nil, NotAcceptable("supported: %s", strings.Join(r.mediaTypes(), ", "))
+	}
+	content, err := r.encode(e, state, data)
+	return e.contentType, content, err
+}
+
+// encode Кодирование данных, изменения записей оборачиваются в конверт ответа
+func (r Response) encode(e encoder, state string, data any) ([]byte, error) {
+	body := data
 	switch state {
-	case Created, Updated, Deleted:
+	case Created, Updated, Deleted, Restored:
+		r.Ok = true
 		r.State = state
 		r.Datetime = time.Now()
-		r.Data = body
-		contentType, content, _ = r.accept(c.Get(consts.HeaderAccept), r)
-	case Read:
-		contentType, content, _ = r.accept(c.Get(consts.HeaderAccept), data)
+		r.Data = data
+		body = r
+	}
+	return e.encode(body)
+}
+
+// problem Ответ с ошибкой в формате RFC 7807: application/problem+xml, если клиент выбрал xml, иначе application/problem+json
+func (r Response) problem(c *ewa.Context, status int, err error) error {
+	p := ProblemOf(err, status)
+	if len(p.Instance) == 0 {
+		p.Instance = c.Path()
+	}
+	contentType, encode := MIMEApplicationProblemJSON, json.Marshal
+	if e, ok := r.negotiate(c.Get(consts.HeaderAccept)); ok && strings.HasSuffix(e.mediaType, "xml") {
+		contentType, encode = MIMEApplicationProblemXML, xml.Marshal
+	}
+	content, err := encode(p)
+	if err != nil {
+		return c.Send(consts.StatusInternalServerError, consts.MIMETextPlainCharsetUTF8, []byte(err.Error()))
+	}
+	return c.Send(p.Status, contentType, content)
+}
+
+// negotiate Выбор кодировщика по заголовку Accept с учётом q-значений.
+// Вес типа определяет самый точный подходящий диапазон, при равных весах - порядок регистрации
+func (r Response) negotiate(header string) (encoder, bool) {
+	encoders := r.encoders
+	if encoders == nil {
+		encoders = defaultEncoders
+	}
+	ranges := parseAccept(header)
+	if len(ranges) == 0 {
+		return encoders[0], true
+	}
+	var (
+		best  encoder
+		bestQ float64
+	)
+	for _, e := range encoders {
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			if s := mr.match(e.mediaType); s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// mediaTypes Список поддерживаемых типов содержимого
+func (r Response) mediaTypes() []string {
+	encoders := r.encoders
+	if encoders == nil {
+		encoders = defaultEncoders
+	}
+	a := make([]string, len(encoders))
+	for i, e := range encoders {
+		a[i] = e.mediaType
+	}
+	return a
+}
+
+// prefers Проверка, что клиент явно указал тип в Accept с наибольшим весом
+func prefers(header, mediaType string) bool {
+	var q, maxQ float64
+	for _, mr := range parseAccept(header) {
+		if mr.mediaType == mediaType && mr.q > q {
+			q = mr.q
+		}
+		if mr.q > maxQ {
+			maxQ = mr.q
+		}
+	}
+	return q > 0 && q >= maxQ
+}
+
+// mediaRange Диапазон типов из заголовка Accept
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// match Точность совпадения диапазона с типом: 2 - тип/подтип, 1 - тип/*, 0 - */*, -1 - не совпадает
+func (m mediaRange) match(mediaType string) int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*")):
+		return 1
+	case m.mediaType == mediaType:
+		return 2
+	}
+	return -1
+}
+
+// parseAccept Разбор заголовка Accept на диапазоны типов
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mr := mediaRange{mediaType: mediaTypeOf(params[0]), q: 1}
+		if len(mr.mediaType) == 0 {
+			continue
+		}
+		for _, param := range params[1:] {
+			a := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(a) == 2 && strings.ToLower(a[0]) == "q" {
+				if q, err := strconv.ParseFloat(a[1], 64); err == nil && q >= 0 && q <= 1 {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
 	}
-	return c.Send(status, contentType, content)
+	return ranges
 }
 
-func (r Response) accept(header string, data any) (contentType string, content []byte, err error) {
-	switch header {
-	case consts.MIMEApplicationXML, consts.MIMEApplicationXMLCharsetUTF8:
-		content, err = xml.Marshal(data)
-		return consts.MIMEApplicationXMLCharsetUTF8, content, err
-	case consts.MIMEApplicationJSON, consts.MIMEApplicationJSONCharsetUTF8:
-		content, err = json.Marshal(data)
-		return consts.MIMEApplicationXMLCharsetUTF8, content, err
+// mediaTypeOf Тип содержимого без параметров в нижнем регистре
+func mediaTypeOf(contentType string) string {
+	if i := strings.Index(contentType, ";"); i > -1 {
+		contentType = contentType[:i]
 	}
-	return consts.MIMEApplicationXMLCharsetUTF8, []byte(fmt.Sprintf("%s", data)), nil
+	return strings.ToLower(strings.TrimSpace(contentType))
 }
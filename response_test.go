@@ -0,0 +1,60 @@
+package crud
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+func TestResponse_Accept(t *testing.T) {
+	send := func(resp IResponse, accept string, state string, data any) *testContext {
+		tc := newTestContext()
+		if len(accept) > 0 {
+			tc.SetHeader(consts.HeaderAccept, accept)
+		}
+		if err := resp.Send(ewa.NewContext(tc), state, 200, data); err != nil {
+			t.Fatal(err)
+		}
+		return tc
+	}
+	r := new(Response)
+
+	tc := send(r, "", Read, Map{"id": 1})
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, tc.response.contentType, consts.MIMEApplicationJSONCharsetUTF8)
+	assertEq(t, string(tc.response.body), `{"id":1}`)
+
+	tc = send(r, "text/html,application/xhtml+xml,*/*;q=0.8", Read, Map{"id": 1})
+	assertEq(t, tc.response.contentType, consts.MIMEApplicationJSONCharsetUTF8)
+
+	tc = send(r, "application/json;q=0.5, application/xml", Created, "result")
+	assertEq(t, tc.response.contentType, consts.MIMEApplicationXMLCharsetUTF8)
+
+	tc = send(r, "application/json;q=0, */*", Read, "result")
+	assertEq(t, tc.response.contentType, consts.MIMEApplicationXMLCharsetUTF8)
+
+	tc = send(r, "text/*", Read, "result")
+	assertEq(t, tc.response.contentType, consts.MIMETextXMLCharsetUTF8)
+
+	tc = send(r, "text/html", Read, Map{"id": 1})
+	assertEq(t, tc.response.status, consts.StatusNotAcceptable)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemJSON)
+	assertEq(t, string(tc.response.body), `{"type":"not-acceptable","title":"Not Acceptable","status":406,"detail":"supported: application/json, application/xml, text/xml","instance":"/"}`)
+
+	tc = send(r, "text/html", Read, fmt.Errorf("failed"))
+	assertEq(t, tc.response.status, 500)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemJSON)
+	assertEq(t, string(tc.response.body), `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed","instance":"/"}`)
+
+	// Регистрация кодировщика
+	r = NewResponse().SetEncoder("text/plain; charset=utf-8", func(data any) ([]byte, error) {
+		return []byte(fmt.Sprint(data)), nil
+	})
+	tc = send(r, "text/plain", Read, "result")
+	assertEq(t, tc.response.contentType, "text/plain; charset=utf-8")
+	assertEq(t, string(tc.response.body), "result")
+	tc = send(r, "", Read, "result")
+	assertEq(t, tc.response.contentType, consts.MIMEApplicationJSONCharsetUTF8)
+}
@@ -1,24 +1,38 @@
 package crud
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/ewa-go/ewa"
 	"github.com/ewa-go/ewa/consts"
 	"github.com/ewa-go/ewa/security"
+	"github.com/ewa-go/jsonschema"
 )
 
 type CRUD struct {
-	FieldIdName string
-	ModelName   string
-	Excludes    []string
-	TableTypes  TableTypes
-	Variables   map[string]any
+	FieldIdName    string
+	ModelName      string
+	Excludes       []string
+	TableTypes     TableTypes
+	Variables      map[string]any
+	Placeholder    Placeholder
+	ExpandArrays   bool
+	Strict         bool
+	TrackChanges   bool
+	SoftDelete     *SoftDelete
+	VersionColumn  string
+	ModifiedColumn string
+	Policies       []Policy
+	CSV            *CSV
+	Schema         *jsonschema.Schema
+	Schemas        map[string]*jsonschema.Schema
 
 	IHandlers
 	IResponse
 	IQueryParam
+	IAudit
 }
 
 var ErrQueryParam = "Укажите поля для уточнения изменения записи! Пример: ../path?name=Name"
@@ -46,6 +60,44 @@ func (r *CRUD) SetIResponse(resp IResponse) *CRUD {
 	return r
 }
 
+// SetPlaceholder Установка стиля плейсхолдеров запроса и раскрытия массивов в отдельные плейсхолдеры
+func (r *CRUD) SetPlaceholder(placeholder Placeholder, expandArrays ...bool) *CRUD {
+	r.Placeholder = placeholder
+	if len(expandArrays) > 0 {
+		r.ExpandArrays = expandArrays[0]
+	}
+	return r
+}
+
+// GetPlaceholder Стиль плейсхолдеров: установленный в CRUD, иначе формата query параметров, иначе обработчиков
+func (r *CRUD) GetPlaceholder() Placeholder {
+	if r.Placeholder != "" {
+		return r.Placeholder
+	}
+	if p, ok := r.IQueryParam.(IPlaceholder); ok && p.DefaultPlaceholder() != "" {
+		return p.DefaultPlaceholder()
+	}
+	if p, ok := r.IHandlers.(IPlaceholder); ok && p.DefaultPlaceholder() != "" {
+		return p.DefaultPlaceholder()
+	}
+	return PlaceholderQuestion
+}
+
+// Query Формирование условия запроса с плейсхолдерами в стиле GetPlaceholder
+func (r *CRUD) Query(q *QueryParams, columns []string) (string, []any) {
+	return r.Bind(r.IQueryParam.Query(q, columns))
+}
+
+// Bind Замена плейсхолдеров ? в запросе на плейсхолдеры в стиле GetPlaceholder.
+// Массивы раскрываются по ExpandArrays или по IExpandArrays формата query параметров
+func (r *CRUD) Bind(query string, args []any) (string, []any) {
+	expand := r.ExpandArrays
+	if e, ok := r.IQueryParam.(IExpandArrays); ok && e.ExpandArrays() {
+		expand = true
+	}
+	return r.GetPlaceholder().Bind(query, args, expand)
+}
+
 // SetVariable Установка интерфейса для аудита
 func (r *CRUD) SetVariable(key string, value any) *CRUD {
 	if r.Variables == nil {
@@ -116,6 +168,8 @@ func (r *CRUD) NewQueryParams(c *ewa.Context, isFilter bool) (*QueryParams, erro
 
 		// Применение фильтра для запроса
 		queryParams.Filter = &filter
+		// Способ подсчёта общего количества записей
+		queryParams.Count = ParseCount(c.Get(HeaderPrefer))
 	}
 
 	paramId := c.Params(r.FieldIdName)
@@ -126,16 +180,22 @@ func (r *CRUD) NewQueryParams(c *ewa.Context, isFilter bool) (*QueryParams, erro
 		}
 		queryParams.ID = qf
 	}
+	var err error
 	c.QueryParams(func(key, value string) {
-		if key == filterParamName {
+		if key == filterParamName || err != nil {
 			return
 		}
-		qf, err := r.QueryFormat(key, value)
+		var qf *QueryParam
+		qf, err = r.QueryFormat(key, value)
 		if err != nil {
+			err = fmt.Errorf("%s: %w", key, err)
 			return
 		}
 		queryParams.Set(qf.Key, qf)
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &queryParams, nil
 }
@@ -152,64 +212,162 @@ func (r *CRUD) ReadHandler(c *ewa.Context, before BeforeHandler, after AfterHand
 		r.SetModelName(r.TableTypes.Get(c.Get(HeaderTableType)))
 	}
 	// Аудит
-	//defer r.Audit(Read, c, r)
+	a := r.audit(c, Read)
+	defer a.done()
 
-	// Вернуть столбцы таблицы
+	// Вернуть описание столбцов таблицы
 	tableInfo := strings.ToLower(c.Get(HeaderTableInfo))
 	if len(tableInfo) > 0 {
-		var fields []string
+		// Список полей возвращает только имена столбцов
 		if tableInfo != "full" {
-			fields = strings.Split(tableInfo, ",")
+			return a.Send(c, Read, consts.StatusOK, r.Columns(r, strings.Split(tableInfo, ",")...))
+		}
+		info, err := r.TableInfo()
+		if err != nil {
+			return a.Send(c, Read, consts.StatusInternalServerError, err)
 		}
-		return r.Send(c, Read, 200, r.Columns(r, fields...))
+		return a.Send(c, Read, consts.StatusOK, info)
 	}
 
 	queryParams, err := r.NewQueryParams(c, true)
+	a.params(queryParams, nil)
 	if err != nil {
-		return r.Send(c, Read, consts.StatusBadRequest, err)
+		return a.Send(c, Read, consts.StatusBadRequest, err)
+	}
+	if err = r.Validate(queryParams, nil); err != nil {
+		return a.Send(c, Read, consts.StatusBadRequest, err)
+	}
+	// Удалённые записи возвращаются только по заголовку With-Deleted
+	if err = r.filterDeleted(c, queryParams); err != nil {
+		return a.Send(c, Read, consts.StatusBadRequest, err)
+	}
+	// Условия политик доступа к строкам
+	if status, err := r.applyPolicies(c, Read, queryParams, nil); err != nil {
+		return a.Send(c, Read, status, err)
+	}
+	// Проверка сортировки по столбцам таблицы
+	if queryParams != nil && queryParams.Filter != nil && len(queryParams.Filter.Orders) > 0 {
+		if _, err = r.Paging(queryParams.Filter, r.Columns(r)); err != nil {
+			return a.Send(c, Read, consts.StatusBadRequest, err)
+		}
+	}
+	// Постраничный вывод по курсору
+	if queryParams != nil {
+		if err = r.Keyset(queryParams.Filter); err != nil {
+			return a.Send(c, Read, consts.StatusBadRequest, err)
+		}
 	}
 	// Обработчик до обращения в бд
 	if before != nil {
 		if status, err := before(c, r, c.Identity, queryParams, nil); err != nil {
-			return r.Send(c, Read, status, err)
+			return a.Send(c, Read, status, err)
+		}
+	}
+
+	// Построчная выдача записей, если обработчик её поддерживает. Обработчику после нужны все записи сразу
+	accept := c.Get(consts.HeaderAccept)
+	isCSV, isNDJSON := prefers(accept, "text/csv"), prefers(accept, MIMEApplicationNDJSON)
+	if s, ok := r.IHandlers.(IStreamHandlers); ok && (isCSV || isNDJSON) && after == nil && (queryParams == nil || queryParams.ID == nil) {
+		var records RecordsFunc = func(ctx context.Context, yield func(record Map) error) (int, error) {
+			return s.StreamRecords(ctx, r, queryParams, yield)
 		}
+		if queryParams != nil {
+			records = queryParams.Filter.Page(records)
+		}
+		if isCSV {
+			return r.SendCSV(c, queryParams, a.stream(records))
+		}
+		return r.SendNDJSON(c, a.stream(records))
+	}
+
+	// Условный запрос по времени изменения записей: If-Modified-Since отвечается 304 без выборки
+	status, notModified, err := r.notModifiedSince(c, queryParams)
+	if err != nil {
+		return a.Send(c, Read, status, err)
+	}
+	if notModified {
+		return a.notModified(c)
 	}
 
 	// Если есть id возвращаем только одну запись
 	if queryParams != nil && queryParams.ID != nil {
 		status, record, err := r.GetRecord(c, r, queryParams)
 		if err != nil {
-			return r.Send(c, Read, status, err) //c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
+			return a.Send(c, Read, status, err) //c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
 		}
 		record.Excludes(r.Excludes...)
+		if queryParams.Filter != nil {
+			record.Excludes(queryParams.Filter.keys...)
+		}
+		var etag string
+		if record != nil {
+			etag = r.ETag(record)
+		}
 
 		// Обработчик после обращению в бд
 		if after != nil {
 			if status, err = after(c, r, c.Identity, queryParams, record); err != nil {
-				return r.Send(c, Read, status, err)
+				return a.Send(c, Read, status, err)
 			}
 		}
 
-		return r.Send(c, Read, status, record)
+		switch {
+		case isCSV:
+			return r.SendCSV(c, queryParams, a.stream(RecordsOf(status, Maps{record})))
+		case isNDJSON:
+			return r.SendNDJSON(c, a.stream(RecordsOf(status, Maps{record})))
+		}
+		return r.sendRead(c, a, status, record, etag, "")
 	}
 
 	// Вернуть записи
 	status, records, total, err := r.GetRecords(c, r, queryParams)
 	if err != nil {
-		return r.Send(c, Read, status, err) //c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
+		return a.Send(c, Read, status, err) //c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
+	}
+	// Заголовок Total по способу подсчёта
+	var totalHeader string
+	if queryParams == nil || queryParams.Count != CountNone {
+		totalHeader = fmt.Sprintf("%d", total)
+		c.Set(HeaderTotal, totalHeader)
+	}
+	// Способ подсчёта применён, если обработчик подсчитал Total этим способом. Без подсчёта Total не отправляется
+	if queryParams != nil && len(queryParams.Count) > 0 &&
+		(queryParams.Count == CountNone || queryParams.Count == queryParams.Counted) {
+		c.Set(HeaderPreferenceApplied, "count="+string(queryParams.Count))
+	}
+	// Заголовки курсоров, записи по курсору назад получены в обратном порядке
+	if queryParams != nil && queryParams.Filter != nil {
+		if f := queryParams.Filter; f.cursor != nil && f.cursor.Prev {
+			for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+				records[i], records[j] = records[j], records[i]
+			}
+		}
+		next, prev := r.Cursors(queryParams.Filter, records)
+		if len(next) > 0 {
+			c.Set(HeaderNextCursor, next)
+		}
+		if len(prev) > 0 {
+			c.Set(HeaderPrevCursor, prev)
+		}
+		records.Excludes(queryParams.Filter.keys...)
 	}
-	// Заголовок Total
-	c.Set(HeaderTotal, fmt.Sprintf("%d", total))
 	records.Excludes(r.Excludes...)
 
 	// Обработчик после обращению в бд
 	if after != nil {
 		if status, err = after(c, r, c.Identity, queryParams, records); err != nil {
-			return r.Send(c, Read, status, err)
+			return a.Send(c, Read, status, err)
 		}
 	}
 
-	return r.Send(c, Read, status, records)
+	switch {
+	case isCSV:
+		return r.SendCSV(c, queryParams, a.stream(RecordsOf(status, records)))
+	case isNDJSON:
+		return r.SendNDJSON(c, a.stream(RecordsOf(status, records)))
+	}
+	return r.sendRead(c, a, status, records, "", totalHeader)
 }
 
 // CreateHandler Обработчик для создания записей
@@ -219,39 +377,51 @@ func (r *CRUD) CreateHandler(c *ewa.Context, before BeforeHandler, after AfterHa
 		r.SetModelName(r.TableTypes.Get(c.Get(HeaderTableType)))
 	}
 	// Аудит
-	//defer r.Audit(Created, c, r)
+	a := r.audit(c, Created)
+	defer a.done()
 
 	body := NewBody(r.FieldIdName).SetIsArray(c.Get(HeaderXContentType) == "array")
 	if err := r.Unmarshal(body, c.Get(consts.HeaderContentType), c.Body()); err != nil {
-		return r.Send(c, Created, consts.StatusBadRequest, err)
+		return a.Send(c, Created, consts.StatusBadRequest, err)
 	}
 
 	queryParams, err := r.NewQueryParams(c, false)
+	a.params(queryParams, body)
 	if err != nil {
-		return r.Send(c, Created, consts.StatusBadRequest, err) //c.SendString(r.String(consts.StatusBadRequest, err.Error()))
+		return a.Send(c, Created, consts.StatusBadRequest, err) //c.SendString(r.String(consts.StatusBadRequest, err.Error()))
+	}
+	if err = r.Validate(queryParams, body); err != nil {
+		return a.Send(c, Created, consts.StatusBadRequest, err)
+	}
+	if err = r.ValidateBody(body, false); err != nil {
+		return a.Send(c, Created, consts.StatusBadRequest, err)
+	}
+	// Поля тела по политикам доступа к строкам
+	if status, err := r.applyPolicies(c, Created, nil, body); err != nil {
+		return a.Send(c, Created, status, err)
 	}
 
 	// Обработчик до обращения в бд
 	if before != nil {
 		var status int
 		if status, err = before(c, r, c.Identity, queryParams, body); err != nil {
-			return r.Send(c, Created, status, err)
+			return a.Send(c, Created, status, err)
 		}
 	}
 
 	status, result, err := r.SetRecord(c, r, body, queryParams)
 	if err != nil {
-		return r.Send(c, Created, status, err) //c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
+		return a.Send(c, Created, status, err) //c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
 	}
 
 	// Обработчик после обращению в бд
 	if after != nil {
 		if status, err = after(c, r, c.Identity, queryParams, result); err != nil {
-			return r.Send(c, Created, status, err)
+			return a.Send(c, Created, status, err)
 		}
 	}
 
-	return r.Send(c, Created, status, result)
+	return a.Send(c, Created, status, result)
 }
 
 // UpdateHandler Обновление записей
@@ -261,45 +431,80 @@ func (r *CRUD) UpdateHandler(c *ewa.Context, before BeforeHandler, after AfterHa
 		r.SetModelName(r.TableTypes.Get(c.Get(HeaderTableType)))
 	}
 	// Аудит
-	//defer r.Audit(Updated, c, r)
+	a := r.audit(c, Updated)
+	defer a.done()
 
 	// Получаем аргументы адресной строки
 	queryParams, err := r.NewQueryParams(c, false)
 	if err != nil {
-		return r.Send(c, Updated, consts.StatusBadRequest, err) //c.SendString(r.String(consts.StatusBadRequest, err.Error()))
+		return a.Send(c, Updated, consts.StatusBadRequest, err) //c.SendString(r.String(consts.StatusBadRequest, err.Error()))
 	}
 
 	if queryParams != nil && queryParams.ID == nil && queryParams.Len() == 0 {
-		return r.Send(c, Updated, consts.StatusBadRequest, ErrQueryParam)
+		return a.Send(c, Updated, consts.StatusBadRequest, Validation(ErrQueryParam))
 	}
 
 	body := NewBody(r.FieldIdName).SetIsArray(c.Get(HeaderXContentType) == "array")
 	if err := r.Unmarshal(body, c.Get(consts.HeaderContentType), c.Body()); err != nil {
-		return r.Send(c, Created, consts.StatusBadRequest, err)
+		return a.Send(c, Created, consts.StatusBadRequest, err)
+	}
+	a.params(queryParams, body)
+	if err = r.Validate(queryParams, body); err != nil {
+		return a.Send(c, Updated, consts.StatusBadRequest, err)
+	}
+	// Изменяются только переданные поля, поэтому обязательные поля не проверяются
+	if err = r.ValidateBody(body, true); err != nil {
+		return a.Send(c, Updated, consts.StatusBadRequest, err)
+	}
+	// Удалённые записи изменяются только по заголовку With-Deleted
+	if err = r.filterDeleted(c, queryParams); err != nil {
+		return a.Send(c, Updated, consts.StatusBadRequest, err)
+	}
+	// Условия и поля тела по политикам доступа к строкам
+	if status, err := r.applyPolicies(c, Updated, queryParams, body); err != nil {
+		return a.Send(c, Updated, status, err)
 	}
 
 	// Обработчик до обращения в бд
 	if before != nil {
 		if status, err := before(c, r, c.Identity, queryParams, body); err != nil {
-			return r.Send(c, Updated, status, err)
+			return a.Send(c, Updated, status, err)
+		}
+	}
+
+	// Проверка версии записи по If-Match
+	if status, err := r.ifMatch(c, queryParams); err != nil {
+		return a.Send(c, Updated, status, err)
+	}
+
+	// Изменения по полям до записи в бд
+	if r.TrackChanges {
+		status, changes, err := r.Changes(c, queryParams, body)
+		if err != nil {
+			return a.Send(c, Updated, status, err)
 		}
+		queryParams.Changes = changes
 	}
 
 	// Пишем данные в бд
 	status, result, err := r.UpdateRecord(c, r, body, queryParams)
 	if err != nil {
-		return r.Send(c, Updated, status, err)
+		return a.Send(c, Updated, status, err)
 		//return c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
 	}
+	if err = r.lostUpdate(c, result); err != nil {
+		return a.Send(c, Updated, consts.StatusPreconditionFailed, err)
+	}
+	a.changes(queryParams.Changes)
 
 	// Обработчик после обращению в бд
 	if after != nil {
 		if status, err = after(c, r, c.Identity, queryParams, result); err != nil {
-			return r.Send(c, Updated, status, err)
+			return a.Send(c, Updated, status, err)
 		}
 	}
 
-	return r.Send(c, Updated, status, result)
+	return a.Send(c, Updated, status, result)
 }
 
 // DeleteHandler Обработчик удаления записей
@@ -309,39 +514,81 @@ func (r *CRUD) DeleteHandler(c *ewa.Context, before BeforeHandler, after AfterHa
 		r.SetModelName(r.TableTypes.Get(c.Get(HeaderTableType)))
 	}
 	// Аудит
-	//defer r.Audit(Deleted, c, r)
+	a := r.audit(c, Deleted)
+	defer a.done()
 
 	// Получаем аргументы адресной строки
 	queryParams, err := r.NewQueryParams(c, false)
+	a.params(queryParams, nil)
 	if err != nil {
-		return r.Send(c, Deleted, consts.StatusBadRequest, err)
+		return a.Send(c, Deleted, consts.StatusBadRequest, err)
 		//return c.SendString(r.String(consts.StatusBadRequest, err.Error()))
 	}
 
 	if queryParams != nil && queryParams.ID == nil && queryParams.Len() == 0 {
-		return r.Send(c, Deleted, consts.StatusBadRequest, ErrQueryParam)
+		return a.Send(c, Deleted, consts.StatusBadRequest, Validation(ErrQueryParam))
+	}
+	if err = r.Validate(queryParams, nil); err != nil {
+		return a.Send(c, Deleted, consts.StatusBadRequest, err)
+	}
+	// Условия политик доступа к строкам
+	if status, err := r.applyPolicies(c, Deleted, queryParams, nil); err != nil {
+		return a.Send(c, Deleted, status, err)
+	}
+	// При мягком удалении ставится отметка удаления
+	var body *Body
+	if r.SoftDelete != nil {
+		if body, err = r.markDeleted(queryParams, true); err != nil {
+			return a.Send(c, Deleted, consts.StatusBadRequest, err)
+		}
 	}
 
 	// Обработчик до обращения в бд
 	if before != nil {
-		if status, err := before(c, r, c.Identity, queryParams, nil); err != nil {
-			return r.Send(c, Deleted, status, err)
+		if status, err := before(c, r, c.Identity, queryParams, body); err != nil {
+			return a.Send(c, Deleted, status, err)
 		}
 	}
 
+	// Проверка версии записи по If-Match
+	if status, err := r.ifMatch(c, queryParams); err != nil {
+		return a.Send(c, Deleted, status, err)
+	}
+
+	// Удаляемые значения до удаления из бд
+	if r.TrackChanges {
+		status, changes, err := r.Changes(c, queryParams, body)
+		if err != nil {
+			return a.Send(c, Deleted, status, err)
+		}
+		queryParams.Changes = changes
+	}
+
 	// Удаление записи
-	status, result, err := r.DeleteRecord(c, r, queryParams)
+	var (
+		status int
+		result any
+	)
+	if body != nil {
+		status, result, err = r.UpdateRecord(c, r, body, queryParams)
+	} else {
+		status, result, err = r.DeleteRecord(c, r, queryParams)
+	}
 	if err != nil {
-		return r.Send(c, Deleted, status, err)
+		return a.Send(c, Deleted, status, err)
 		//return c.SendString(r.String(consts.StatusUnprocessableEntity, err.Error()))
 	}
+	if err = r.lostUpdate(c, result); err != nil {
+		return a.Send(c, Deleted, consts.StatusPreconditionFailed, err)
+	}
+	a.changes(queryParams.Changes)
 
 	// Обработчик после обращению в бд
 	if after != nil {
 		if status, err = after(c, r, c.Identity, queryParams, result); err != nil {
-			return r.Send(c, Deleted, status, err)
+			return a.Send(c, Deleted, status, err)
 		}
 	}
 
-	return r.Send(c, Deleted, status, result)
+	return a.Send(c, Deleted, status, result)
 }
@@ -1,9 +1,14 @@
 package crud
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
@@ -11,6 +16,141 @@ import (
 	"github.com/ewa-go/ewa/security"
 )
 
+// testContext Реализация ewa.IContext для тестов маршрутов
+type testContext struct {
+	headers  map[string]string
+	params   map[string]string
+	query    [][2]string
+	body     []byte
+	response struct {
+		status      int
+		contentType string
+		headers     map[string]string
+		body        []byte
+	}
+}
+
+func newTestContext() *testContext {
+	c := &testContext{
+		headers: map[string]string{},
+		params:  map[string]string{},
+	}
+	c.response.headers = map[string]string{}
+	return c
+}
+
+func (c *testContext) SetHeader(key, value string) *testContext {
+	c.headers[key] = value
+	return c
+}
+
+func (c *testContext) SetParam(key, value string) *testContext {
+	c.params[key] = value
+	return c
+}
+
+func (c *testContext) AddQuery(key, value string) *testContext {
+	c.query = append(c.query, [2]string{key, value})
+	return c
+}
+
+func (c *testContext) SetBody(body string) *testContext {
+	c.body = []byte(body)
+	return c
+}
+
+func (c *testContext) Render(string, interface{}, ...string) error { return nil }
+func (c *testContext) Params(key string, defaultValue ...string) string {
+	if v, ok := c.params[key]; ok {
+		return v
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+func (c *testContext) Get(key string, defaultValue ...string) string {
+	if v, ok := c.headers[key]; ok {
+		return v
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+func (c *testContext) Set(key string, value string) { c.response.headers[key] = value }
+func (c *testContext) SendStatus(code int) error {
+	c.response.status = code
+	return nil
+}
+func (c *testContext) Send(code int, contentType string, b []byte) error {
+	c.response.status = code
+	c.response.contentType = contentType
+	c.response.body = b
+	return nil
+}
+func (c *testContext) SendString(code int, s string) error {
+	return c.Send(code, "text/plain", []byte(s))
+}
+func (c *testContext) SendFile(string) error                          { return nil }
+func (c *testContext) SaveFile(*multipart.FileHeader, string) error   { return nil }
+func (c *testContext) Cookies(string) string                          { return "" }
+func (c *testContext) SetCookie(*http.Cookie)                         {}
+func (c *testContext) ClearCookie(string)                             {}
+func (c *testContext) Redirect(string, int) error                     { return nil }
+func (c *testContext) Path() string                                   { return "/" }
+func (c *testContext) Body() []byte                                   { return c.body }
+func (c *testContext) BodyParser(out interface{}) error               { return json.Unmarshal(c.body, out) }
+func (c *testContext) Hostname() string                               { return "localhost" }
+func (c *testContext) FormValue(string) string                        { return "" }
+func (c *testContext) FormFile(string) (*multipart.FileHeader, error) { return nil, nil }
+func (c *testContext) Scheme() string                                 { return "http" }
+func (c *testContext) MultipartForm() (*multipart.Form, error)        { return nil, nil }
+func (c *testContext) IP() string                                     { return "127.0.0.1" }
+func (c *testContext) IPs() []string                                  { return nil }
+func (c *testContext) Context() context.Context                       { return context.Background() }
+func (c *testContext) Ctx() interface{}                               { return nil }
+func (c *testContext) Method() string                                 { return http.MethodGet }
+func (c *testContext) Request() interface{}                           { return nil }
+func (c *testContext) HttpRequest() *http.Request                     { return nil }
+func (c *testContext) SendStream(code int, contentType string, stream io.Reader) error {
+	b, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	return c.Send(code, contentType, b)
+}
+func (c *testContext) JSON(code int, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return c.Send(code, "application/json", b)
+}
+func (c *testContext) QueryParam(name string, defaultValue ...string) string {
+	for _, q := range c.query {
+		if q[0] == name {
+			return q[1]
+		}
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+func (c *testContext) QueryValues() url.Values {
+	values := url.Values{}
+	for _, q := range c.query {
+		values.Add(q[0], q[1])
+	}
+	return values
+}
+func (c *testContext) QueryParams(f func(key, value string)) {
+	for _, q := range c.query {
+		f(q[0], q[1])
+	}
+}
+
 type Handlers struct{}
 
 func (h *Handlers) Columns(r *CRUD, fields ...string) []string {
@@ -136,6 +276,7 @@ func TestGet(t *testing.T) {
 			Username: "username",
 			Datetime: time.Now(),
 		},
+		IContext: newTestContext(),
 	}
 
 	if err := route.Handler(ctx); err != nil {
@@ -170,9 +311,19 @@ func TestCustomHandler(t *testing.T) {
 			Username: "username",
 			Datetime: time.Now(),
 		},
+		IContext: newTestContext(),
 	}
 
 	if err := route.Handler(ctx); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestReadHandler_InvalidOrder(t *testing.T) {
+	tc := newTestContext().AddQuery(filterParamName, `{"orders":["age desc"]}`)
+	ctx := &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+}
@@ -0,0 +1,434 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ewa-go/jsonschema"
+)
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// SetSchema Установка JSON схемы тела запроса для создания и изменения записей
+func (r *CRUD) SetSchema(schema *jsonschema.Schema) *CRUD {
+	r.Schema = schema
+	return r
+}
+
+// SetTableSchema Установка JSON схемы тела запроса для таблицы, например выбранной по Table-Type
+func (r *CRUD) SetTableSchema(modelName string, schema *jsonschema.Schema) *CRUD {
+	if r.Schemas == nil {
+		r.Schemas = make(map[string]*jsonschema.Schema)
+	}
+	r.Schemas[modelName] = schema
+	return r
+}
+
+// GetSchema JSON схема текущей таблицы, иначе общая схема
+func (r *CRUD) GetSchema() *jsonschema.Schema {
+	if schema, ok := r.Schemas[r.ModelName]; ok {
+		return schema
+	}
+	return r.Schema
+}
+
+// ValidateBody Проверка тела запроса, в том числе каждого элемента массива, по JSON схеме.
+// В режиме partial обязательные поля записи не проверяются.
+// Возвращает ErrValidation со всеми ошибками, путь к значению указывается в формате JSON Pointer
+func (r *CRUD) ValidateBody(body *Body, partial bool) error {
+	schema := r.GetSchema()
+	if schema == nil || body == nil {
+		return nil
+	}
+	v := &schemaValidator{root: schema, partial: partial}
+	if body.IsArray {
+		for i, record := range body.Array {
+			v.validate(schema, map[string]interface{}(record), "/"+strconv.Itoa(i), true)
+		}
+	} else {
+		v.validate(schema, body.Data, "", true)
+	}
+	if len(v.errors) > 0 {
+		return Validation("body does not match schema", v.errors...)
+	}
+	return nil
+}
+
+// schemaValidator Проверка значения по подмножеству ключевых слов JSON Schema 2020-12
+type schemaValidator struct {
+	root    *jsonschema.Schema
+	partial bool
+	errors  []FieldError
+}
+
+func (v *schemaValidator) fail(path, format string, a ...any) {
+	if len(path) == 0 {
+		path = "/"
+	}
+	v.errors = append(v.errors, FieldError{Field: path, Message: fmt.Sprintf(format, a...)})
+}
+
+// valid Проверка без накопления ошибок, для anyOf, oneOf и not
+func (v *schemaValidator) valid(s *jsonschema.Schema, value any, path string, record bool) bool {
+	sub := &schemaValidator{root: v.root, partial: v.partial}
+	sub.validate(s, value, path, record)
+	return len(sub.errors) == 0
+}
+
+// validate Проверка значения value по схеме s. record - значение является записью тела запроса
+func (v *schemaValidator) validate(s *jsonschema.Schema, value any, path string, record bool) {
+	if s == nil {
+		return
+	}
+	if b, ok := schemaBool(s); ok {
+		if !b {
+			v.fail(path, "value is not allowed")
+		}
+		return
+	}
+	if len(s.Ref) > 0 {
+		ref, err := v.resolve(s.Ref)
+		if err != nil {
+			v.fail(path, "%s", err)
+			return
+		}
+		v.validate(ref, value, path, record)
+	}
+	for _, sub := range s.AllOf {
+		v.validate(sub, value, path, record)
+	}
+	if len(s.AnyOf) > 0 {
+		var ok bool
+		for _, sub := range s.AnyOf {
+			if ok = v.valid(sub, value, path, record); ok {
+				break
+			}
+		}
+		if !ok {
+			v.fail(path, "value does not match any schema")
+		}
+	}
+	if len(s.OneOf) > 0 {
+		var n int
+		for _, sub := range s.OneOf {
+			if v.valid(sub, value, path, record) {
+				n++
+			}
+		}
+		if n != 1 {
+			v.fail(path, "value must match exactly one schema")
+		}
+	}
+	if s.Not != nil && v.valid(s.Not, value, path, record) {
+		v.fail(path, "value must not match schema")
+	}
+
+	if len(s.Type) > 0 && !isType(s.Type, value) {
+		v.fail(path, "expected %s", s.Type)
+		return
+	}
+	if len(s.Enum) > 0 {
+		var ok bool
+		for _, e := range s.Enum {
+			if ok = jsonEqual(e, value); ok {
+				break
+			}
+		}
+		if !ok {
+			v.fail(path, "value must be one of %v", s.Enum)
+		}
+	}
+	if s.Const != nil && !jsonEqual(s.Const, value) {
+		v.fail(path, "value must be %v", s.Const)
+	}
+
+	switch t := value.(type) {
+	case string:
+		v.validateString(s, t, path)
+	case map[string]interface{}:
+		v.validateObject(s, t, path, record)
+	case []interface{}:
+		v.validateArray(s, t, path)
+	default:
+		if n, ok := toFloat(value); ok {
+			v.validateNumber(s, n, path)
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(s *jsonschema.Schema, value, path string) {
+	n := utf8.RuneCountInString(value)
+	if s.MinLength > 0 && n < s.MinLength {
+		v.fail(path, "length must be at least %d", s.MinLength)
+	}
+	if s.MaxLength > 0 && n > s.MaxLength {
+		v.fail(path, "length must be at most %d", s.MaxLength)
+	}
+	if len(s.Pattern) > 0 {
+		rgx, err := regexp.Compile(s.Pattern)
+		if err != nil || !rgx.MatchString(value) {
+			v.fail(path, "value does not match pattern %s", s.Pattern)
+		}
+	}
+	if len(s.Format) > 0 && !isFormat(s.Format, value) {
+		v.fail(path, "value is not a valid %s", s.Format)
+	}
+}
+
+// validateNumber Проверка числа. Нулевые minimum, maximum и multipleOf в jsonschema.Schema не отличить от отсутствующих
+func (v *schemaValidator) validateNumber(s *jsonschema.Schema, value float64, path string) {
+	if s.Minimum != 0 || s.ExclusiveMinimum {
+		switch min := float64(s.Minimum); {
+		case s.ExclusiveMinimum && value <= min:
+			v.fail(path, "value must be greater than %d", s.Minimum)
+		case value < min:
+			v.fail(path, "value must be greater than or equal to %d", s.Minimum)
+		}
+	}
+	if s.Maximum != 0 || s.ExclusiveMaximum {
+		switch max := float64(s.Maximum); {
+		case s.ExclusiveMaximum && value >= max:
+			v.fail(path, "value must be less than %d", s.Maximum)
+		case value > max:
+			v.fail(path, "value must be less than or equal to %d", s.Maximum)
+		}
+	}
+	if s.MultipleOf > 0 && math.Mod(value, float64(s.MultipleOf)) != 0 {
+		v.fail(path, "value must be a multiple of %d", s.MultipleOf)
+	}
+}
+
+func (v *schemaValidator) validateObject(s *jsonschema.Schema, value map[string]interface{}, path string, record bool) {
+	if !(v.partial && record) {
+		for _, key := range s.Required {
+			if _, ok := value[key]; !ok {
+				v.fail(path+"/"+key, "required")
+			}
+		}
+	}
+	if s.MinProperties > 0 && len(value) < s.MinProperties {
+		v.fail(path, "object must have at least %d properties", s.MinProperties)
+	}
+	if s.MaxProperties > 0 && len(value) > s.MaxProperties {
+		v.fail(path, "object must have at most %d properties", s.MaxProperties)
+	}
+	for _, key := range sortedKeys(value) {
+		for _, dependent := range s.DependentRequired[key] {
+			if _, ok := value[dependent]; !ok {
+				v.fail(path+"/"+dependent, "required by %s", key)
+			}
+		}
+		matched := false
+		if prop, ok := property(s, key); ok {
+			matched = true
+			v.validate(prop, value[key], path+"/"+key, false)
+		}
+		for pattern, prop := range s.PatternProperties {
+			if rgx, err := regexp.Compile(pattern); err == nil && rgx.MatchString(key) {
+				matched = true
+				v.validate(prop, value[key], path+"/"+key, false)
+			}
+		}
+		if !matched && s.AdditionalProperties != nil {
+			if b, ok := schemaBool(s.AdditionalProperties); ok && !b {
+				v.fail(path+"/"+key, "unknown field")
+			} else {
+				v.validate(s.AdditionalProperties, value[key], path+"/"+key, false)
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(s *jsonschema.Schema, value []interface{}, path string) {
+	if s.MinItems > 0 && len(value) < s.MinItems {
+		v.fail(path, "array must have at least %d items", s.MinItems)
+	}
+	if s.MaxItems > 0 && len(value) > s.MaxItems {
+		v.fail(path, "array must have at most %d items", s.MaxItems)
+	}
+	for i, item := range value {
+		itemPath := path + "/" + strconv.Itoa(i)
+		if i < len(s.PrefixItems) {
+			v.validate(s.PrefixItems[i], item, itemPath, false)
+		} else {
+			v.validate(s.Items, item, itemPath, false)
+		}
+		if s.UniqueItems {
+			for j := 0; j < i; j++ {
+				if jsonEqual(value[j], item) {
+					v.fail(itemPath, "array items must be unique")
+					break
+				}
+			}
+		}
+	}
+	if s.Contains != nil {
+		var n uint
+		for i, item := range value {
+			if v.valid(s.Contains, item, path+"/"+strconv.Itoa(i), false) {
+				n++
+			}
+		}
+		min := s.MinContains
+		if min == 0 {
+			min = 1
+		}
+		if n < min {
+			v.fail(path, "array must contain at least %d matching items", min)
+		}
+		if s.MaxContains > 0 && n > s.MaxContains {
+			v.fail(path, "array must contain at most %d matching items", s.MaxContains)
+		}
+	}
+}
+
+// resolve Схема по ссылке #/$defs/Name
+func (v *schemaValidator) resolve(ref string) (*jsonschema.Schema, error) {
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if strings.HasPrefix(ref, prefix) {
+			if s, ok := v.root.Definitions[strings.TrimPrefix(ref, prefix)]; ok {
+				return s, nil
+			}
+		}
+	}
+	if ref == "#" {
+		return v.root, nil
+	}
+	return nil, fmt.Errorf("unresolved schema reference %s", ref)
+}
+
+// property Схема свойства. После разбора схемы из json свойства хранятся как orderedmap
+func property(s *jsonschema.Schema, key string) (*jsonschema.Schema, bool) {
+	if s.Properties == nil {
+		return nil, false
+	}
+	value, ok := s.Properties.Get(key)
+	if !ok {
+		return nil, false
+	}
+	switch t := value.(type) {
+	case *jsonschema.Schema:
+		return t, true
+	case jsonschema.Schema:
+		return &t, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	prop := new(jsonschema.Schema)
+	if err = json.Unmarshal(data, prop); err != nil {
+		return nil, false
+	}
+	return prop, true
+}
+
+// schemaBool Значение схемы true или false
+func schemaBool(s *jsonschema.Schema) (value bool, ok bool) {
+	switch {
+	case reflect.DeepEqual(s, jsonschema.TrueSchema):
+		return true, true
+	case reflect.DeepEqual(s, jsonschema.FalseSchema):
+		return false, true
+	}
+	return false, false
+}
+
+// isType Проверка типа значения JSON
+func isType(t string, value any) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		n, ok := toFloat(value)
+		return ok && n == math.Trunc(n)
+	}
+	return true
+}
+
+// isFormat Проверка строки по формату
+func isFormat(format, value string) bool {
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "time":
+		_, err = time.Parse("15:04:05Z07:00", value)
+	case "email":
+		_, err = mail.ParseAddress(value)
+	case "uuid":
+		return uuidRegexp.MatchString(value)
+	case "ipv4":
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+	case "ipv6":
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ":")
+	case "uri":
+		var u *url.URL
+		u, err = url.Parse(value)
+		return err == nil && len(u.Scheme) > 0
+	}
+	return err == nil
+}
+
+// toFloat Число из значения тела запроса
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// jsonEqual Сравнение значений в представлении JSON, чтобы 1 и 1.0 были равны
+func jsonEqual(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	return err == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,83 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/jsonschema"
+)
+
+type schemaUser struct {
+	ID    int      `json:"id,omitempty"`
+	Name  string   `json:"name" jsonschema:"minLength=2"`
+	Email string   `json:"email,omitempty" jsonschema:"format=email"`
+	Role  string   `json:"role,omitempty" jsonschema:"enum=admin,enum=user"`
+	Tags  []string `json:"tags,omitempty" jsonschema:"uniqueItems=true"`
+}
+
+func TestValidateBody(t *testing.T) {
+	r := getCRUD().SetSchema(jsonschema.Reflect(&schemaUser{}))
+
+	body := NewBody("id")
+	body.Data = map[string]interface{}{"id": 1.5, "email": "bad", "role": "guest", "tags": []interface{}{"a", "a"}, "extra": true}
+	err := r.ValidateBody(body, false)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	var p *Problem
+	errors.As(err, &p)
+	var fields []string
+	for _, e := range p.Errors {
+		fields = append(fields, e.Field+": "+e.Message)
+	}
+	assertArrayStringEq(t, fields, []string{
+		"/name: required",
+		"/email: value is not a valid email",
+		"/extra: unknown field",
+		"/id: expected integer",
+		"/role: value must be one of [admin user]",
+		"/tags/1: array items must be unique",
+	})
+
+	// Частичная проверка без обязательных полей
+	body.Data = map[string]interface{}{"email": "user@example.com"}
+	if err = r.ValidateBody(body, true); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.ValidateBody(body, false); err == nil {
+		t.Fatal("expected required error")
+	}
+
+	// Каждый элемент массива
+	body.SetIsArray(true)
+	body.Array = []map[string]interface{}{{"name": "Name"}, {"name": "N"}}
+	err = r.ValidateBody(body, false)
+	errors.As(err, &p)
+	assertEq(t, len(p.Errors), 1)
+	assertEq(t, p.Errors[0].Field, "/1/name")
+}
+
+func TestValidateBody_TableSchema(t *testing.T) {
+	var schema jsonschema.Schema
+	err := json.Unmarshal([]byte(`{"type":"object","required":["count"],"properties":{"count":{"type":"integer","maximum":10}},"additionalProperties":false}`), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(h).SetFieldIdName("id").SetTableTypeTable("schema.table", true).SetTableSchema("schema.table", &schema)
+
+	tc := newTestContext().SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).SetBody(`{"count": 11}`)
+	if err = r.CreateHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+	assertEq(t, string(tc.response.body), `{"type":"validation","title":"Validation Failed","status":400,"detail":"body does not match schema","instance":"/","errors":[{"field":"/count","message":"value must be less than or equal to 10"}]}`)
+
+	tc = newTestContext().SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).SetBody(`{"count": 5}`)
+	if err = r.CreateHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+}
@@ -0,0 +1,162 @@
+package crud
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+// SoftDelete Настройки мягкого удаления: вместо удаления записи в столбце Column ставится отметка
+type SoftDelete struct {
+	// Column Столбец отметки удаления
+	Column string
+	// Boolean Столбец логический, иначе в нём хранится время удаления
+	Boolean bool
+	// Allow Разрешение на получение удалённых записей по заголовку With-Deleted. Если не задано, то запрещено
+	Allow func(c *ewa.Context, i *security.Identity) bool
+}
+
+// SetSoftDelete Установка мягкого удаления по столбцу column: логическому или с временем удаления
+func (r *CRUD) SetSoftDelete(column string, boolean bool, allow ...func(c *ewa.Context, i *security.Identity) bool) *CRUD {
+	r.SoftDelete = &SoftDelete{Column: column, Boolean: boolean}
+	if len(allow) > 0 {
+		r.SoftDelete.Allow = allow[0]
+	}
+	return r
+}
+
+// mark Значение отметки удаления или её снятия
+func (s *SoftDelete) mark(deleted bool) any {
+	switch {
+	case s.Boolean:
+		return deleted
+	case deleted:
+		return time.Now()
+	}
+	return nil
+}
+
+// where Добавление условия на удалённые или не удалённые записи
+func (s *SoftDelete) where(r *CRUD, params *QueryParams, deleted bool) error {
+	var (
+		param *QueryParam
+		err   error
+	)
+	switch {
+	case s.Boolean && deleted:
+		param, err = r.QueryFormat(s.Column, "true")
+	case s.Boolean:
+		// is not true, чтобы NULL в столбце тоже считался не удалённой записью
+		if param, err = r.QueryFormat(s.Column, "null"); err == nil {
+			param.Znak = "is not true"
+		}
+	case deleted:
+		param, err = r.QueryFormat(s.Column+"[!]", "null")
+	default:
+		param, err = r.QueryFormat(s.Column, "null")
+	}
+	if err != nil {
+		return err
+	}
+	params.And(param)
+	return nil
+}
+
+// filterDeleted Условие на не удалённые записи. По заголовку With-Deleted: true возвращаются все записи, only - только удалённые
+func (r *CRUD) filterDeleted(c *ewa.Context, params *QueryParams) error {
+	if r.SoftDelete == nil || params == nil {
+		return nil
+	}
+	header := strings.ToLower(c.Get(HeaderWithDeleted))
+	switch header {
+	case "":
+		return r.SoftDelete.where(r, params, false)
+	case "true", "only":
+	default:
+		return Validation(HeaderWithDeleted + " must be true or only")
+	}
+	if r.SoftDelete.Allow == nil || !r.SoftDelete.Allow(c, c.Identity) {
+		return Forbidden("deleted records are not allowed")
+	}
+	if header == "only" {
+		return r.SoftDelete.where(r, params, true)
+	}
+	return nil
+}
+
+// markDeleted Тело для установки или снятия отметки удаления через UpdateRecord.
+// Отметка меняется только у записей в противоположном состоянии
+func (r *CRUD) markDeleted(params *QueryParams, deleted bool) (*Body, error) {
+	if err := r.SoftDelete.where(r, params, !deleted); err != nil {
+		return nil, err
+	}
+	return NewBody(r.FieldIdName).SetField(r.SoftDelete.Column, r.SoftDelete.mark(deleted)), nil
+}
+
+// RestoreHandler Восстановление удалённых записей: снятие отметки мягкого удаления
+func (r *CRUD) RestoreHandler(c *ewa.Context, before BeforeHandler, after AfterHandler) error {
+
+	if r.TableTypes != nil {
+		r.SetModelName(r.TableTypes.Get(c.Get(HeaderTableType)))
+	}
+	// Аудит
+	a := r.audit(c, Restored)
+	defer a.done()
+
+	if r.SoftDelete == nil {
+		return a.Send(c, Restored, consts.StatusForbidden, Forbidden("soft delete is not enabled for %s", r.ModelName))
+	}
+
+	queryParams, err := r.NewQueryParams(c, false)
+	a.params(queryParams, nil)
+	if err != nil {
+		return a.Send(c, Restored, consts.StatusBadRequest, err)
+	}
+	if queryParams != nil && queryParams.ID == nil && queryParams.Len() == 0 {
+		return a.Send(c, Restored, consts.StatusBadRequest, Validation(ErrQueryParam))
+	}
+	if err = r.Validate(queryParams, nil); err != nil {
+		return a.Send(c, Restored, consts.StatusBadRequest, err)
+	}
+	if status, err := r.applyPolicies(c, Restored, queryParams, nil); err != nil {
+		return a.Send(c, Restored, status, err)
+	}
+	body, err := r.markDeleted(queryParams, false)
+	if err != nil {
+		return a.Send(c, Restored, consts.StatusBadRequest, err)
+	}
+
+	// Обработчик до обращения в бд
+	if before != nil {
+		if status, err := before(c, r, c.Identity, queryParams, body); err != nil {
+			return a.Send(c, Restored, status, err)
+		}
+	}
+
+	// Изменения по полям до записи в бд
+	if r.TrackChanges {
+		status, changes, err := r.Changes(c, queryParams, body)
+		if err != nil {
+			return a.Send(c, Restored, status, err)
+		}
+		queryParams.Changes = changes
+	}
+
+	status, result, err := r.UpdateRecord(c, r, body, queryParams)
+	if err != nil {
+		return a.Send(c, Restored, status, err)
+	}
+	a.changes(queryParams.Changes)
+
+	// Обработчик после обращению в бд
+	if after != nil {
+		if status, err = after(c, r, c.Identity, queryParams, result); err != nil {
+			return a.Send(c, Restored, status, err)
+		}
+	}
+
+	return a.Send(c, Restored, status, result)
+}
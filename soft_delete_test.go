@@ -0,0 +1,148 @@
+package crud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/ewa-go/ewa/security"
+)
+
+func TestQueryParams_And(t *testing.T) {
+	r := getCRUD()
+	q := new(QueryParams)
+	q.Set("name", QueryFormat(r, "name", "a"))
+	q.Set("name", QueryFormat(r, "[|]name", "b"))
+	q.And(QueryFormat(r, "deleted", "false"))
+	where, values := q.Where(func(param *QueryParam) string {
+		return param.Key + " " + param.Znak
+	})
+	assertEq(t, where, "(name = ? or name = ?) and deleted = ?")
+	assertArrayEq(t, values, []any{"a", "b", false})
+
+	// ID и условие клиента с [|] в одной группе
+	q = new(QueryParams)
+	q.ID = QueryFormat(r, "id", "1")
+	q.Set("name", QueryFormat(r, "[|]name", "a"))
+	q.And(QueryFormat(r, "deleted", "false"))
+	where, values = q.Where(func(param *QueryParam) string {
+		return param.Key + " " + param.Znak
+	})
+	assertEq(t, where, "(id = ? or name = ?) and deleted = ?")
+	assertArrayEq(t, values, []any{"1", "a", false})
+
+	// Соединение первого условия клиента сохраняется
+	q = new(QueryParams)
+	q.ID = QueryFormat(r, "id", "1")
+	q.Set("name", QueryFormat(r, "[|]name", "a"))
+	q.Set("age", QueryFormat(r, "age", "2"))
+	q.And(QueryFormat(r, "deleted", "false"))
+	q.And(QueryFormat(r, "owner_id", "7"))
+	where, _ = q.Where(func(param *QueryParam) string {
+		return param.Key + " " + param.Znak
+	})
+	assertEq(t, where, "(id = ? or name = ? and age = ?) and deleted = ? and owner_id = ?")
+}
+
+func TestReadHandler_SoftDelete(t *testing.T) {
+	d, r := newFakeSQL()
+	r.SetSoftDelete("deleted_at", false)
+
+	tc := newTestContext().AddQuery("name", "a")
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, d.queries[1], `SELECT * FROM "public"."users" WHERE "name" = $1 and "deleted_at" is null`)
+
+	// Удалённые записи без разрешения
+	tc = newTestContext().SetHeader(HeaderWithDeleted, "true")
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 403)
+
+	r.SetSoftDelete("deleted_at", false, func(c *ewa.Context, i *security.Identity) bool {
+		return i != nil && i.Username == "admin"
+	})
+	tc = newTestContext().SetHeader(HeaderWithDeleted, "only")
+	ctx := &ewa.Context{IContext: tc, Identity: &security.Identity{Username: "admin"}}
+	if err := r.ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, d.queries[3], `SELECT * FROM "public"."users" WHERE "deleted_at" is not null`)
+
+	// Условие [|] рядом с ID не возвращает удалённые записи
+	tc = newTestContext().SetParam("id", "1").AddQuery("[|]name", "a")
+	if err := r.ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[len(d.queries)-1], `SELECT * FROM "public"."users" WHERE ("id" = $1 or "name" = $2) and "deleted_at" is null LIMIT 1`)
+}
+
+func TestDeleteHandler_SoftDelete(t *testing.T) {
+	d, r := newFakeSQL()
+	r.SetSoftDelete("deleted_at", false)
+
+	tc := newTestContext().SetParam("id", "1")
+	if err := r.DeleteHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, d.queries[0], `UPDATE "public"."users" SET "deleted_at" = $1 WHERE "id" = $2 and "deleted_at" is null`)
+	_, ok := d.args[0][0].(time.Time)
+	assertEq(t, ok, true)
+
+	tc = newTestContext().SetParam("id", "1")
+	if err := r.RestoreHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, d.queries[1], `UPDATE "public"."users" SET "deleted_at" = $1 WHERE "id" = $2 and "deleted_at" is not null`)
+	assertEq(t, d.args[1][0], nil)
+
+	// Логический столбец
+	r.SetSoftDelete("deleted", true)
+	tc = newTestContext().SetParam("id", "1")
+	if err := r.DeleteHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[2], `UPDATE "public"."users" SET "deleted" = $1 WHERE "id" = $2 and "deleted" is not true`)
+	assertArrayStringEq(t, d.args[2], []any{true, int64(1)})
+
+	// Восстановление без мягкого удаления
+	r.SoftDelete = nil
+	tc = newTestContext().SetParam("id", "1")
+	if err := r.RestoreHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, consts.StatusForbidden)
+}
+
+func TestUpdateHandler_SoftDelete(t *testing.T) {
+	d, r := newFakeSQL()
+	r.SetSoftDelete("deleted_at", false)
+
+	tc := newTestContext().
+		SetParam("id", "1").
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"name":"Name2"}`)
+	if err := r.UpdateHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, d.queries[0], `UPDATE "public"."users" SET "name" = $1 WHERE "id" = $2 and "deleted_at" is null`)
+
+	// Удалённые записи без разрешения
+	tc = newTestContext().
+		SetParam("id", "1").
+		SetHeader(HeaderWithDeleted, "true").
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"name":"Name2"}`)
+	if err := r.UpdateHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 403)
+}
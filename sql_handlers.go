@@ -0,0 +1,623 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+	"github.com/lib/pq"
+)
+
+// SQLHandlers Реализация IHandlers поверх database/sql только для PostgreSQL: кавычки идентификаторов, LIMIT, RETURNING,
+// EXPLAIN (FORMAT JSON) и information_schema в синтаксисе PostgreSQL. С форматами MySQL, SQL Server и SQLite не используется
+type SQLHandlers struct {
+	DB *sql.DB
+	// Placeholder Стиль плейсхолдеров по-умолчанию, для lib/pq - $n
+	Placeholder Placeholder
+
+	mu      sync.RWMutex
+	columns map[string][]string
+}
+
+var ErrEmptyCondition = errors.New("пустое условие запроса")
+
+// NewSQLHandlers Инициализация обработчиков для database/sql
+func NewSQLHandlers(db *sql.DB) *SQLHandlers {
+	return &SQLHandlers{
+		DB:          db,
+		Placeholder: PlaceholderDollar,
+		columns:     make(map[string][]string),
+	}
+}
+
+// SetPlaceholder Установка стиля плейсхолдеров по-умолчанию
+func (h *SQLHandlers) SetPlaceholder(placeholder Placeholder) *SQLHandlers {
+	h.Placeholder = placeholder
+	return h
+}
+
+// DefaultPlaceholder Стиль плейсхолдеров по-умолчанию
+func (h *SQLHandlers) DefaultPlaceholder() Placeholder {
+	return h.Placeholder
+}
+
+// SetColumns Установка столбцов модели без обращения к information_schema
+func (h *SQLHandlers) SetColumns(modelName string, columns ...string) *SQLHandlers {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.columns == nil {
+		h.columns = make(map[string][]string)
+	}
+	h.columns[modelName] = columns
+	return h
+}
+
+// Columns Вернуть столбцы таблицы. Если переданы поля, то возвращаются только они
+func (h *SQLHandlers) Columns(r *CRUD, fields ...string) []string {
+	columns, err := h.loadColumns(context.Background(), r)
+	if err != nil {
+		return nil
+	}
+	if len(fields) == 0 {
+		return columns
+	}
+	var result []string
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		for _, column := range columns {
+			if column == field {
+				result = append(result, column)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// GetRecord Вернуть одну запись
+func (h *SQLHandlers) GetRecord(c *ewa.Context, r *CRUD, params *QueryParams) (int, Map, error) {
+	where, args := r.IQueryParam.Query(params, r.Columns(r))
+	query := fmt.Sprintf("SELECT %s FROM %s", h.projection(params), quoteIdent(r.ModelName))
+	if len(where) > 0 {
+		query += " WHERE " + where
+	}
+	query += " LIMIT 1"
+
+	query, args = r.Bind(query, args)
+	rows, err := h.DB.QueryContext(contextOf(c), query, args...)
+	if err != nil {
+		return statusOf(err), nil, err
+	}
+	defer rows.Close()
+
+	records, err := scanRows(rows)
+	if err != nil {
+		return statusOf(err), nil, err
+	}
+	if len(records) == 0 {
+		return consts.StatusNotFound, nil, NotFound("%s not found", r.ModelName)
+	}
+	return consts.StatusOK, records[0], nil
+}
+
+// GetRecords Вернуть записи и их общее количество
+func (h *SQLHandlers) GetRecords(c *ewa.Context, r *CRUD, params *QueryParams) (int, Maps, int64, error) {
+	// Query изменяет ключи параметров, поэтому условие формируется один раз
+	cond, args := r.IQueryParam.Query(params, r.Columns(r))
+	var where string
+	if len(cond) > 0 {
+		where = " WHERE " + cond
+	}
+	table := quoteIdent(r.ModelName)
+
+	var total int64
+	count := CountExact
+	if params != nil && len(params.Count) > 0 {
+		count = params.Count
+	}
+	switch count {
+	case CountNone:
+	case CountEstimated:
+		var err error
+		if total, err = h.estimate(contextOf(c), r, table+where, args); err != nil {
+			return statusOf(err), nil, 0, err
+		}
+	default:
+		query, values := r.Bind("SELECT count(*) FROM "+table+where, args)
+		if err := h.DB.QueryRowContext(contextOf(c), query, values...).Scan(&total); err != nil {
+			return statusOf(err), nil, 0, err
+		}
+	}
+	if params != nil {
+		params.Counted = count
+	}
+
+	query, values, err := h.selectQuery(r, params, table, cond, args)
+	if err != nil {
+		return consts.StatusBadRequest, nil, 0, err
+	}
+	rows, err := h.DB.QueryContext(contextOf(c), query, values...)
+	if err != nil {
+		return statusOf(err), nil, 0, err
+	}
+	defer rows.Close()
+
+	records, err := scanRows(rows)
+	if err != nil {
+		return statusOf(err), nil, 0, err
+	}
+	return consts.StatusOK, records, total, nil
+}
+
+// StreamRecords Построчная выдача записей без подсчёта общего количества
+func (h *SQLHandlers) StreamRecords(ctx context.Context, r *CRUD, params *QueryParams, yield func(record Map) error) (int, error) {
+	cond, args := r.IQueryParam.Query(params, r.Columns(r))
+	query, values, err := h.selectQuery(r, params, quoteIdent(r.ModelName), cond, args)
+	if err != nil {
+		return consts.StatusBadRequest, err
+	}
+	rows, err := h.DB.QueryContext(ctx, query, values...)
+	if err != nil {
+		return statusOf(err), err
+	}
+	defer rows.Close()
+
+	if err = eachRow(rows, yield); err != nil {
+		return statusOf(err), err
+	}
+	return consts.StatusOK, nil
+}
+
+// selectQuery Запрос выборки записей по условию cond с условием по курсору, сортировкой и постраничным выводом
+func (h *SQLHandlers) selectQuery(r *CRUD, params *QueryParams, table, cond string, args []any) (string, []any, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", h.projection(params), table)
+	// Условие по курсору не влияет на общее количество записей
+	var seek string
+	if params != nil {
+		var seekArgs []any
+		seek, seekArgs = r.Seek(params.Filter)
+		args = append(args, seekArgs...)
+	}
+	switch {
+	case len(cond) > 0 && len(seek) > 0:
+		// Условия клиента могут соединяться через or, поэтому курсор ограничивает их целиком
+		query += " WHERE (" + cond + ") and " + seek
+	case len(cond) > 0:
+		query += " WHERE " + cond
+	case len(seek) > 0:
+		query += " WHERE " + seek
+	}
+	if params != nil {
+		paging, err := r.Paging(params.Filter, r.Columns(r))
+		if err != nil {
+			return "", nil, err
+		}
+		if len(paging) > 0 {
+			query += " " + paging
+		}
+	}
+	query, values := r.Bind(query, args)
+	return query, values, nil
+}
+
+// estimate Оценка количества записей по плану запроса без его выполнения
+func (h *SQLHandlers) estimate(ctx context.Context, r *CRUD, from string, args []any) (int64, error) {
+	var plan []byte
+	query, values := r.Bind("EXPLAIN (FORMAT JSON) SELECT 1 FROM "+from, args)
+	if err := h.DB.QueryRowContext(ctx, query, values...).Scan(&plan); err != nil {
+		return 0, err
+	}
+	var result []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &result); err != nil || len(result) == 0 {
+		return 0, fmt.Errorf("invalid query plan")
+	}
+	return int64(result[0].Plan.Rows), nil
+}
+
+// LastModified Наибольшее время изменения выбираемых записей по столбцу ModifiedColumn
+func (h *SQLHandlers) LastModified(c *ewa.Context, r *CRUD, params *QueryParams) (int, time.Time, error) {
+	if len(r.ModifiedColumn) == 0 {
+		return consts.StatusOK, time.Time{}, nil
+	}
+	where, args := r.IQueryParam.Query(params, r.Columns(r))
+	query := fmt.Sprintf("SELECT max(%s) FROM %s", quoteIdent(r.ModifiedColumn), quoteIdent(r.ModelName))
+	if len(where) > 0 {
+		query += " WHERE " + where
+	}
+	var modified sql.NullTime
+	query, args = r.Bind(query, args)
+	if err := h.DB.QueryRowContext(contextOf(c), query, args...).Scan(&modified); err != nil {
+		return statusOf(err), time.Time{}, err
+	}
+	return consts.StatusOK, modified.Time, nil
+}
+
+// SetRecord Добавить запись или массив записей
+func (h *SQLHandlers) SetRecord(c *ewa.Context, r *CRUD, data *Body, params *QueryParams) (int, any, error) {
+	if !data.IsArray {
+		result, err := h.insert(contextOf(c), h.DB, r, data.ToMap(), data.FieldIDName)
+		if err != nil {
+			return statusOf(err), nil, err
+		}
+		return consts.StatusCreated, result, nil
+	}
+
+	var results []any
+	err := h.transaction(contextOf(c), func(tx *sql.Tx) error {
+		for i := range data.Array {
+			result, err := h.insert(contextOf(c), tx, r, data.ToArrayMap(i), data.FieldIDName)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return statusOf(err), nil, err
+	}
+	return consts.StatusCreated, results, nil
+}
+
+// ColumnsInfo Описание столбцов таблицы из information_schema: тип, допустимость NULL, значение по-умолчанию, первичный ключ
+// и комментарий к столбцу
+func (h *SQLHandlers) ColumnsInfo(r *CRUD, fields ...string) ([]ColumnInfo, error) {
+	where, args := tableCondition(r.ModelName, "c.")
+	query, args := r.Bind(`SELECT c.column_name, c.data_type, c.is_nullable, c.column_default, `+
+		`CASE WHEN EXISTS (SELECT 1 FROM information_schema.table_constraints tc `+
+		`JOIN information_schema.key_column_usage k ON k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema AND k.table_name = tc.table_name `+
+		`WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema AND tc.table_name = c.table_name AND k.column_name = c.column_name) `+
+		`THEN 1 ELSE 0 END, col_description((quote_ident(c.table_schema)||'.'||quote_ident(c.table_name))::regclass, c.ordinal_position) `+
+		`FROM information_schema.columns c WHERE `+where+` ORDER BY c.ordinal_position`, args)
+	rows, err := h.DB.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var (
+			column     ColumnInfo
+			nullable   string
+			def        sql.NullString
+			primaryKey int
+			comment    sql.NullString
+		)
+		if err = rows.Scan(&column.Name, &column.Type, &nullable, &def, &primaryKey, &comment); err != nil {
+			return nil, err
+		}
+		if len(fields) > 0 && !isExcluded(column.Name, fields) {
+			continue
+		}
+		column.Nullable = strings.EqualFold(nullable, "YES")
+		if def.Valid {
+			column.Default = &def.String
+		}
+		column.PrimaryKey = primaryKey == 1
+		column.Description = comment.String
+		column.Filterable = true
+		column.Sortable = isSortable(column.Type)
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// isSortable Проверка, что по столбцу такого типа можно сортировать
+func isSortable(columnType string) bool {
+	t := strings.ToLower(columnType)
+	return !strings.Contains(t, "json") && t != "xml" && t != "bytea"
+}
+
+// UpdateRecord Изменить записи по условию. Для массива записей условием служит поле FieldIDName каждой записи
+func (h *SQLHandlers) UpdateRecord(c *ewa.Context, r *CRUD, data *Body, params *QueryParams) (int, any, error) {
+	where, args := r.IQueryParam.Query(params, r.Columns(r))
+	if !data.IsArray {
+		if len(where) == 0 {
+			return consts.StatusBadRequest, nil, ErrEmptyCondition
+		}
+		affected, err := h.update(contextOf(c), h.DB, r, data.ToMap(), where, args)
+		if err != nil {
+			return statusOf(err), nil, err
+		}
+		return consts.StatusOK, affected, nil
+	}
+
+	var affected int64
+	err := h.transaction(contextOf(c), func(tx *sql.Tx) error {
+		for i := range data.Array {
+			record := data.ToArrayMap(i)
+			id, ok := record[data.FieldIDName]
+			if !ok {
+				return fmt.Errorf("в записи %d не указано поле %s", i, data.FieldIDName)
+			}
+			delete(record, data.FieldIDName)
+			w := quoteIdent(data.FieldIDName) + " = ?"
+			if len(where) > 0 {
+				w = "(" + where + ") and " + w
+			}
+			n, err := h.update(contextOf(c), tx, r, record, w, append(append([]any{}, args...), id))
+			if err != nil {
+				return err
+			}
+			affected += n
+		}
+		return nil
+	})
+	if err != nil {
+		return statusOf(err), nil, err
+	}
+	return consts.StatusOK, affected, nil
+}
+
+// DeleteRecord Удалить записи по условию
+func (h *SQLHandlers) DeleteRecord(c *ewa.Context, r *CRUD, params *QueryParams) (int, any, error) {
+	where, args := r.IQueryParam.Query(params, r.Columns(r))
+	if len(where) == 0 {
+		return consts.StatusBadRequest, nil, ErrEmptyCondition
+	}
+	query, args := r.Bind("DELETE FROM "+quoteIdent(r.ModelName)+" WHERE "+where, args)
+	result, err := h.DB.ExecContext(contextOf(c), query, args...)
+	if err != nil {
+		return statusOf(err), nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return statusOf(err), nil, err
+	}
+	return consts.StatusOK, affected, nil
+}
+
+// Unmarshal Разбор тела запроса
+func (h *SQLHandlers) Unmarshal(body *Body, contentType string, data []byte) error {
+	return functions{}.Unmarshal(body, contentType, data)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (h *SQLHandlers) insert(ctx context.Context, db execer, r *CRUD, record map[string]interface{}, fieldIDName string) (any, error) {
+	keys := sortedKeys(record)
+	if len(keys) == 0 {
+		return nil, errors.New("пустые данные")
+	}
+	var (
+		columns, marks []string
+		args           []any
+	)
+	for _, key := range keys {
+		columns = append(columns, quoteIdent(key))
+		marks = append(marks, "?")
+		args = append(args, bodyValue(record[key]))
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(r.ModelName), strings.Join(columns, ", "), strings.Join(marks, ", "))
+	if len(fieldIDName) > 0 {
+		var id any
+		query, args = r.Bind(query+" RETURNING "+quoteIdent(fieldIDName), args)
+		if err := db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+			return nil, err
+		}
+		return normalize(id), nil
+	}
+	query, args = r.Bind(query, args)
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result.RowsAffected()
+}
+
+func (h *SQLHandlers) update(ctx context.Context, db execer, r *CRUD, record map[string]interface{}, where string, whereArgs []any) (int64, error) {
+	keys := sortedKeys(record)
+	if len(keys) == 0 {
+		return 0, errors.New("пустые данные")
+	}
+	var (
+		sets []string
+		args []any
+	)
+	for _, key := range keys {
+		// Версия записи только увеличивается
+		if key == r.VersionColumn {
+			continue
+		}
+		sets = append(sets, quoteIdent(key)+" = ?")
+		args = append(args, bodyValue(record[key]))
+	}
+	if len(r.VersionColumn) > 0 {
+		version := quoteIdent(r.VersionColumn)
+		sets = append(sets, version+" = "+version+" + 1")
+	}
+	query, args := r.Bind(fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteIdent(r.ModelName), strings.Join(sets, ", "), where), append(args, whereArgs...))
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (h *SQLHandlers) transaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// projection Список полей выборки из Filter.Select
+func (h *SQLHandlers) projection(params *QueryParams) string {
+	if params == nil {
+		return "*"
+	}
+	columns := params.Filter.Select()
+	if len(columns) == 0 {
+		return "*"
+	}
+	fields := make([]string, len(columns))
+	for i, field := range columns {
+		fields[i] = quoteIdent(field)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// tableCondition Условие выборки из information_schema по имени таблицы со схемой через точку
+func tableCondition(modelName, alias string) (string, []any) {
+	if i := strings.LastIndex(modelName, "."); i > -1 {
+		return alias + "table_name = ? AND " + alias + "table_schema = ?", []any{modelName[i+1:], modelName[:i]}
+	}
+	return alias + "table_name = ?", []any{modelName}
+}
+
+func (h *SQLHandlers) loadColumns(ctx context.Context, r *CRUD) ([]string, error) {
+	modelName := r.ModelName
+	h.mu.RLock()
+	columns, ok := h.columns[modelName]
+	h.mu.RUnlock()
+	if ok {
+		return columns, nil
+	}
+
+	where, args := tableCondition(modelName, "")
+	query, args := r.Bind("SELECT column_name FROM information_schema.columns WHERE "+where+" ORDER BY ordinal_position", args)
+	rows, err := h.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	h.SetColumns(modelName, columns...)
+	return columns, nil
+}
+
+// scanRows Чтение строк результата в Maps
+func scanRows(rows *sql.Rows) (Maps, error) {
+	records := Maps{}
+	err := eachRow(rows, func(record Map) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// eachRow Чтение строк результата по одной
+func eachRow(rows *sql.Rows, fn func(record Map) error) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			return err
+		}
+		record := make(Map, len(columns))
+		for i, column := range columns {
+			record[column] = normalize(values[i])
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// quoteIdent Экранирование идентификатора, в том числе schema.table
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// statusOf Код ответа по ошибке базы данных
+func statusOf(err error) int {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "22", "42":
+			// Неверные данные или синтаксис запроса
+			return consts.StatusBadRequest
+		case "23":
+			// Нарушение ограничений целостности
+			return consts.StatusConflict
+		}
+	}
+	return consts.StatusInternalServerError
+}
+
+// bodyValue Значение поля тела для записи в бд: объекты пишутся как json, массивы как массивы postgres
+func bodyValue(value any) any {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return value
+		}
+		return string(b)
+	case []interface{}:
+		return pq.Array(v)
+	}
+	return value
+}
+
+func normalize(value any) any {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+func contextOf(c *ewa.Context) context.Context {
+	if c == nil || c.IContext == nil {
+		return context.Background()
+	}
+	if ctx := c.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,245 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ewa-go/ewa"
+)
+
+// fakeDriver Драйвер database/sql, который запоминает запросы и возвращает заготовленные строки
+type fakeDriver struct {
+	queries []string
+	args    [][]driver.Value
+	rows    func(query string) ([]string, [][]driver.Value)
+	// noRows Изменения не затрагивают строк
+	noRows bool
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return &fakeConn{d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *fakeConn) Commit() error                             { return nil }
+func (c *fakeConn) Rollback() error                           { return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+	if s.d.noRows {
+		return driver.RowsAffected(0), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+	columns, values := s.d.rows(s.query)
+	return &fakeRows{columns: columns, values: values}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newFakeSQL() (*fakeDriver, *CRUD) {
+	d := &fakeDriver{
+		rows: func(query string) ([]string, [][]driver.Value) {
+			switch {
+			case strings.HasPrefix(query, "SELECT count(*)"):
+				return []string{"count"}, [][]driver.Value{{int64(2)}}
+			case strings.HasPrefix(query, "EXPLAIN"):
+				return []string{"QUERY PLAN"}, [][]driver.Value{{[]byte(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 1234}}]`)}}
+			case strings.HasPrefix(query, "SELECT c.column_name"):
+				return []string{"column_name", "data_type", "is_nullable", "column_default", "primary_key", "col_description"}, [][]driver.Value{
+					{[]byte("id"), []byte("integer"), []byte("NO"), []byte("nextval('users_id_seq'::regclass)"), int64(1), nil},
+					{[]byte("name"), []byte("text"), []byte("YES"), nil, int64(0), []byte("User name")},
+					{[]byte("data"), []byte("jsonb"), []byte("YES"), nil, int64(0), nil},
+				}
+			case strings.HasPrefix(query, "INSERT"):
+				return []string{"id"}, [][]driver.Value{{int64(10)}}
+			}
+			return []string{"id", "name"}, [][]driver.Value{{int64(1), []byte("Name1")}, {int64(2), []byte("Name2")}}
+		},
+	}
+	h := NewSQLHandlers(sql.OpenDB(d)).SetColumns("public.users", "id", "name")
+	return d, New(h).SetModelName("public.users").SetFieldIdName("id")
+}
+
+func TestSQLHandlers_GetRecords(t *testing.T) {
+	d, r := newFakeSQL()
+	c := ewa.NewContext(newTestContext())
+
+	q := &QueryParams{Filter: &Filter{Fields: []string{"id", "name"}, Orders: []string{"name desc"}, Limit: 10, Offset: 20}}
+	q.ID = QueryFormat(r, "id[:]", "[1|5]::int")
+	q.Set("name", QueryFormat(r, "name", "[a,b]"))
+	status, records, total, err := r.GetRecords(c, r, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, status, 200)
+	assertEq(t, total, int64(2))
+	assertEq(t, len(records), 2)
+	assertEq(t, records[0]["name"], "Name1")
+	assertEq(t, d.queries[0], `SELECT count(*) FROM "public"."users" WHERE "id" between $1 and $2 and "name" = any($3)`)
+	assertEq(t, d.queries[1], `SELECT "id", "name" FROM "public"."users" WHERE "id" between $1 and $2 and "name" = any($3) ORDER BY "name" DESC LIMIT 10 OFFSET 20`)
+	assertArrayStringEq(t, d.args[1], []driver.Value{int64(1), int64(5), `{"a","b"}`})
+
+	// Раскрытие массивов в отдельные плейсхолдеры
+	r.SetPlaceholder(PlaceholderDollar, true)
+	q = &QueryParams{}
+	q.Set("name", QueryFormat(r, "name", "[a,b]"))
+	if _, _, _, err = r.GetRecords(c, r, q); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[2], `SELECT count(*) FROM "public"."users" WHERE "name" in($1,$2)`)
+
+	// Курсор не влияет на общее количество записей
+	r.SetPlaceholder(PlaceholderDollar, false)
+	q = &QueryParams{Filter: &Filter{Limit: 2, Cursor: (&Cursor{Values: []any{3}}).Encode()}}
+	if err = r.Keyset(q.Filter); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err = r.GetRecords(c, r, q); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[4], `SELECT count(*) FROM "public"."users"`)
+	assertEq(t, d.queries[5], `SELECT * FROM "public"."users" WHERE ("id") > ($1) ORDER BY "id" ASC LIMIT 2`)
+
+	// Условия через or ограничиваются курсором целиком, столбцы курсора выбираются всегда
+	q = &QueryParams{Filter: &Filter{Fields: []string{"name"}, Limit: 2, Cursor: (&Cursor{Values: []any{3}}).Encode()}}
+	q.Set("name", QueryFormat(r, "name", "a"))
+	q.Set("name", QueryFormat(r, "[|]name", "b"))
+	if err = r.Keyset(q.Filter); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err = r.GetRecords(c, r, q); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, d.queries[7], `SELECT "name", "id" FROM "public"."users" WHERE ("name" = $1 or "name" = $2) and ("id") > ($3) ORDER BY "id" ASC LIMIT 2`)
+
+	q = &QueryParams{Filter: &Filter{Orders: []string{"name; drop table users"}}}
+	status, _, _, err = r.GetRecords(c, r, q)
+	if err == nil {
+		t.Fatal("expected order error")
+	}
+	assertEq(t, status, 400)
+}
+
+func TestSQLHandlers_GetRecordsCount(t *testing.T) {
+	d, r := newFakeSQL()
+	c := ewa.NewContext(newTestContext())
+
+	q := &QueryParams{Count: CountEstimated}
+	q.Set("name", QueryFormat(r, "name", "Name1"))
+	_, _, total, err := r.GetRecords(c, r, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, total, int64(1234))
+	assertEq(t, d.queries[0], `EXPLAIN (FORMAT JSON) SELECT 1 FROM "public"."users" WHERE "name" = $1`)
+	assertEq(t, q.Counted, CountEstimated)
+
+	q.Count = CountNone
+	_, records, total, err := r.GetRecords(c, r, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, total, int64(0))
+	assertEq(t, len(records), 2)
+	assertEq(t, len(d.queries), 3)
+}
+
+func TestSQLHandlers_GetRecord(t *testing.T) {
+	d, r := newFakeSQL()
+	c := ewa.NewContext(newTestContext())
+
+	q := &QueryParams{ID: QueryFormat(r, "id", "1::int")}
+	status, record, err := r.GetRecord(c, r, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, status, 200)
+	assertEq(t, record["id"], int64(1))
+	assertEq(t, d.queries[0], `SELECT * FROM "public"."users" WHERE "id" = $1 LIMIT 1`)
+}
+
+func TestSQLHandlers_SetRecord(t *testing.T) {
+	d, r := newFakeSQL()
+	c := ewa.NewContext(newTestContext())
+
+	body := NewBody("id", Field{"author", "username"})
+	body.Data = map[string]interface{}{"name": "Name"}
+	status, result, err := r.SetRecord(c, r, body, &QueryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, status, 201)
+	assertEq(t, result, int64(10))
+	assertEq(t, d.queries[0], `INSERT INTO "public"."users" ("author", "name") VALUES ($1, $2) RETURNING "id"`)
+
+	body = NewBody("", Field{"author", "username"}).SetIsArray(true)
+	body.Array = []map[string]interface{}{{"name": "Name1"}, {"name": "Name2"}}
+	_, result, err = r.SetRecord(c, r, body, &QueryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertArrayStringEq(t, result, []any{int64(1), int64(1)})
+	assertEq(t, len(d.queries), 3)
+	assertEq(t, d.queries[2], `INSERT INTO "public"."users" ("author", "name") VALUES ($1, $2)`)
+}
+
+func TestSQLHandlers_UpdateDeleteRecord(t *testing.T) {
+	d, r := newFakeSQL()
+	c := ewa.NewContext(newTestContext())
+
+	q := &QueryParams{ID: QueryFormat(r, "id", "1::int")}
+	body := NewBody("id")
+	body.Data = map[string]interface{}{"name": "Name"}
+	_, result, err := r.UpdateRecord(c, r, body, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, result, int64(1))
+	assertEq(t, d.queries[0], `UPDATE "public"."users" SET "name" = $1 WHERE "id" = $2`)
+
+	q = &QueryParams{}
+	q.Set("name", QueryFormat(r, "name", "Name"))
+	_, result, err = r.DeleteRecord(c, r, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, result, int64(1))
+	assertEq(t, d.queries[1], `DELETE FROM "public"."users" WHERE "name" = $1`)
+
+	status, _, err := r.DeleteRecord(c, r, &QueryParams{})
+	assertEq(t, err, ErrEmptyCondition)
+	assertEq(t, status, 400)
+}
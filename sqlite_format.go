@@ -0,0 +1,272 @@
+package crud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLiteFormat Форматирование query параметров для SQLite
+type SQLiteFormat struct {
+	baseFormat
+
+	// TimeStorage Формат хранения даты и времени: текст ISO8601 или юлианский день
+	TimeStorage SQLiteTime
+}
+
+type SQLiteTime string
+
+const (
+	SQLiteTimeText SQLiteTime = "text"
+	SQLiteTimeReal SQLiteTime = "real"
+)
+
+// julianUnixEpoch Юлианский день начала эпохи unix
+const julianUnixEpoch = 2440587.5
+
+// DefaultPlaceholder Плейсхолдеры ?
+func (s *SQLiteFormat) DefaultPlaceholder() Placeholder {
+	return PlaceholderQuestion
+}
+
+// ExpandArrays Драйверы SQLite не передают массивы, поэтому in(?) раскрывается поэлементно
+func (s *SQLiteFormat) ExpandArrays() bool {
+	return true
+}
+
+func (s *SQLiteFormat) Pattern() string {
+	return `\[(->|->>|>|<|>-|<-|!|<>|array|&&|!array|!&&|~|!~|~\*|!~\*|\+|!\+|%|!%|:|[aA-zZ]+)]$`
+}
+
+func (s *SQLiteFormat) Format(r *CRUD, q *QueryParam) (*QueryParam, error) {
+
+	switch q.Znak {
+	case "!":
+		q.Znak = "!="
+	case ">-":
+		q.Znak = ">="
+	case "<-":
+		q.Znak = "<="
+	case "%", "!%":
+		// like в SQLite не учитывает регистр, поэтому используем glob как в postgres
+		if v, ok := q.Value.(string); ok {
+			q.Value = likeToGlob(v)
+		}
+		if q.Znak == "!%" {
+			q.Znak = "NOT GLOB ?"
+			return q, nil
+		}
+		q.Znak = "GLOB ?"
+		return q, nil
+	case "~", "!~", "~*", "!~*", "+", "!+", "array", "&&", "!array", "!&&":
+		return nil, fmt.Errorf("operator [%s] is not supported by sqlite", q.Znak)
+	case "->", "->>":
+		v, ok := q.Value.(string)
+		if !ok {
+			break
+		}
+		a := strings.SplitN(v, "=", 2)
+		if len(a) != 2 {
+			break
+		}
+		qf, err := r.QueryFormat(a[0], a[1])
+		if err != nil {
+			return nil, err
+		}
+		if !jsonPathRegexp.MatchString(qf.Key) {
+			return nil, fmt.Errorf("invalid json path %s", qf.Key)
+		}
+		q.Znak = "json_extract(" + keyMark + ", '$." + qf.Key + "') " + qf.Znak
+		q.Value = qf.Value
+		q.Type = qf.Type
+		return q, nil
+	}
+
+	if q.Value == nil {
+		switch q.Znak {
+		case "=":
+			q.Znak = "is null"
+		case "!=", "<>":
+			q.Znak = "is not null"
+		}
+		return q, nil
+	}
+	if q.IsArray() {
+		switch q.Znak {
+		case "=":
+			q.Znak = "in(?)"
+		case "!=", "<>":
+			q.Znak = "not in(?)"
+		}
+		return q, nil
+	}
+	if q.IsRange() {
+		q.Znak = "between ? and ?"
+		return q, nil
+	}
+
+	q.Znak += " ?"
+
+	return q, nil
+}
+
+func (s *SQLiteFormat) Query(q *QueryParams, columns []string) (query string, values []any) {
+	var fields []string
+	// Если нет параметров, то выходим
+	if q.Len() == 0 && q.ID == nil {
+		return "", nil
+	}
+
+	// Формирование полей для поиска везде OR
+	if vals, ok := q.m[AllFieldsParamName]; ok && len(vals) > 0 {
+		value := vals[0]
+		if q.Filter != nil && len(q.Filter.Fields) > 0 {
+			columns = q.Filter.Fields
+		}
+		for _, column := range columns {
+			if _, ok = q.m[column]; !ok {
+				key := s.quote(column)
+				if value.IsQuotes {
+					key = "CAST(" + key + " AS TEXT)"
+				}
+				fields = append(fields, s.condition(key, value.Znak))
+				values = append(values, value.Value)
+			}
+		}
+	}
+	if len(fields) > 0 {
+		query = "(" + strings.Join(fields, " or ") + ")"
+	}
+	// Заполняем строку запроса и значения для неё по дереву условий
+	v, args := q.Where(func(param *QueryParam) string {
+		key := param.Key
+		if param.IsQuotes {
+			key = s.quote(key)
+		}
+		return s.condition(key, param.Znak)
+	})
+	values = append(values, args...)
+	if len(v) > 0 {
+		if len(query) > 0 {
+			query += " and " + v
+		} else {
+			query = v
+		}
+	}
+
+	// Избавляемся от nil значений
+	var vals []any
+	for _, value := range values {
+		if value != nil {
+			vals = append(vals, value)
+		}
+	}
+
+	return query, vals
+}
+
+// Paging Сортировка и постраничный вывод ORDER BY ... LIMIT ... OFFSET ...
+func (s *SQLiteFormat) Paging(f *Filter, columns []string) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	orders, err := ParseOrders(f.Orders, columns)
+	if err != nil {
+		return "", err
+	}
+	var query []string
+	if len(orders) > 0 {
+		a := make([]string, len(orders))
+		for i, o := range orders {
+			a[i] = s.quote(o.Field) + " " + o.Direction()
+			if len(o.Nulls) > 0 {
+				a[i] += " NULLS " + o.Nulls
+			}
+		}
+		query = append(query, "ORDER BY "+strings.Join(a, ", "))
+	}
+	switch {
+	case f.Limit > 0:
+		query = append(query, "LIMIT "+strconv.Itoa(f.Limit))
+	case f.Offset > 0:
+		// OFFSET без LIMIT в SQLite недопустим
+		query = append(query, "LIMIT -1")
+	}
+	if f.Offset > 0 {
+		query = append(query, "OFFSET "+strconv.Itoa(f.Offset))
+	}
+	return strings.Join(query, " "), nil
+}
+
+// Cast Приведение переменной к типу данных. Дата и время приводятся к формату хранения SQLite
+func (s *SQLiteFormat) Cast(value string, q *QueryParam) error {
+	if err := s.baseFormat.Cast(value, q); err != nil {
+		return err
+	}
+	var layout string
+	switch q.DataType {
+	case "date":
+		layout = time.DateOnly
+	case "time":
+		layout = time.TimeOnly
+	case "datetime":
+		layout = time.DateTime
+	default:
+		return nil
+	}
+	switch v := q.Value.(type) {
+	case time.Time:
+		q.Value = s.timeValue(v, layout)
+	case []time.Time:
+		a := make([]any, len(v))
+		for i, t := range v {
+			a[i] = s.timeValue(t, layout)
+		}
+		q.Value = a
+	}
+	return nil
+}
+
+func (s *SQLiteFormat) timeValue(t time.Time, layout string) any {
+	if s.TimeStorage == SQLiteTimeReal {
+		return float64(t.UnixMilli())/float64(24*time.Hour/time.Millisecond) + julianUnixEpoch
+	}
+	return t.Format(layout)
+}
+
+// Seek Условие постраничного вывода по курсору (a, b) > (?, ?)
+func (s *SQLiteFormat) Seek(f *Filter) (string, []any) {
+	return seekCondition(f, s.quote, true)
+}
+
+// condition Подстановка поля в выражение оператора
+func (s *SQLiteFormat) condition(key, znak string) string {
+	if strings.Contains(znak, keyMark) {
+		return strings.ReplaceAll(znak, keyMark, key)
+	}
+	return strings.Trim(key+" "+znak, " ")
+}
+
+// quote Экранирование идентификатора двойными кавычками
+func (s *SQLiteFormat) quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// likeToGlob Перевод шаблона like в шаблон glob
+func likeToGlob(pattern string) string {
+	var b strings.Builder
+	for _, ch := range pattern {
+		switch ch {
+		case '%':
+			b.WriteByte('*')
+		case '_':
+			b.WriteByte('?')
+		case '*', '?', '[':
+			b.WriteString("[" + string(ch) + "]")
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
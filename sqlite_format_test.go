@@ -0,0 +1,62 @@
+package crud
+
+import (
+	"testing"
+)
+
+func getSQLiteCRUD() *CRUD {
+	return New(new(functions)).SetIQueryParam(new(SQLiteFormat))
+}
+
+func TestSQLiteParams(t *testing.T) {
+	r := getSQLiteCRUD()
+	q := &QueryParams{}
+	q.ID = QueryFormat(r, "id", "1::int")
+	q.Set("name", QueryFormat(r, "name[%]", "Им%_*"))
+	query, values := r.Query(q, r.Columns(r))
+	assertEq(t, query, `"id" = ? and "name" GLOB ?`)
+	assertArrayEq(t, []any{1, "Им*?[*]"}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "2::int")
+	q.Set("*", QueryFormat(r, "*[!%]", "Зна%"))
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, `(CAST("id" AS TEXT) NOT GLOB ? or CAST("name" AS TEXT) NOT GLOB ?) and "id" = ?`)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "3::int")
+	q.Set("result", QueryFormat(r, "result[->>]", "type[>]=2"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, `"id" = ? and json_extract("result", '$.type') > ?`)
+	assertArrayEq(t, []any{3, "2"}, values)
+
+	// Диапазон и массив передаются отдельными значениями
+	q = &QueryParams{}
+	q.Set("age", QueryFormat(r, "age[:]", "[1|5]::int"))
+	q.Set("name", QueryFormat(r, "name[!]", "[a,b]"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, `"age" between ? and ? and "name" not in(?,?)`)
+	assertArrayEq(t, []any{1, 5, "a", "b"}, values)
+
+	for _, key := range []string{"name[~]", "name[+]", "tags[&&]", "tags[!array]"} {
+		if _, err := r.QueryFormat(key, "[a,b]"); err == nil {
+			t.Fatalf("expected error for %s", key)
+		}
+	}
+}
+
+func TestSQLiteCast(t *testing.T) {
+	r := getSQLiteCRUD()
+	q, err := r.QueryFormat("created[:]", "[2025-03-28 00:00:00|2025-03-29 00:00:00]::datetime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertArrayStringEq(t, q.Value, []any{"2025-03-28 00:00:00", "2025-03-29 00:00:00"})
+
+	r.SetIQueryParam(&SQLiteFormat{TimeStorage: SQLiteTimeReal})
+	q, err = r.QueryFormat("created", "1970-01-02::date")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, q.Value, 2440588.5)
+}
@@ -0,0 +1,220 @@
+package crud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SQLServerFormat Форматирование query параметров для MS SQL Server.
+// Плейсхолдеры в запросе по-умолчанию нумеруются как @p1..@pN
+type SQLServerFormat struct {
+	baseFormat
+}
+
+func (s *SQLServerFormat) Pattern() string {
+	return `\[(->|->>|>|<|>-|<-|!|<>|array|&&|!array|!&&|~|!~|~\*|!~\*|\+|!\+|%|!%|:|[aA-zZ]+)]$`
+}
+
+func (s *SQLServerFormat) Format(r *CRUD, q *QueryParam) (*QueryParam, error) {
+
+	switch q.Znak {
+	case "!":
+		q.Znak = "<>"
+	case ">-":
+		q.Znak = ">="
+	case "<-":
+		q.Znak = "<="
+	case "%":
+		q.Znak = "LIKE ?"
+		return q, nil
+	case "!%":
+		q.Znak = "NOT LIKE ?"
+		return q, nil
+	case "~", "!~", "~*", "!~*", "+", "!+", "array", "&&", "!array", "!&&":
+		return nil, fmt.Errorf("operator [%s] is not supported by sql server", q.Znak)
+	case "->", "->>":
+		v, ok := q.Value.(string)
+		if !ok {
+			break
+		}
+		a := strings.SplitN(v, "=", 2)
+		if len(a) != 2 {
+			break
+		}
+		qf, err := r.QueryFormat(a[0], a[1])
+		if err != nil {
+			return nil, err
+		}
+		if !jsonPathRegexp.MatchString(qf.Key) {
+			return nil, fmt.Errorf("invalid json path %s", qf.Key)
+		}
+		// JSON_QUERY возвращает только объекты и массивы, для скалярных значений он даёт NULL
+		fn := "JSON_VALUE"
+		if v, ok := qf.Value.(string); ok && q.Znak == "->" && isJSONContainer(v) {
+			fn = "JSON_QUERY"
+		}
+		q.Znak = fn + "(" + keyMark + ", '$." + qf.Key + "') " + qf.Znak
+		q.Value = qf.Value
+		q.Type = qf.Type
+		return q, nil
+	}
+
+	if q.Value == nil {
+		switch q.Znak {
+		case "=":
+			q.Znak = "is null"
+		case "<>", "!=":
+			q.Znak = "is not null"
+		}
+		return q, nil
+	}
+	if q.IsArray() {
+		switch q.Znak {
+		case "=":
+			q.Znak = "in(?)"
+		case "<>", "!=":
+			q.Znak = "not in(?)"
+		}
+		return q, nil
+	}
+	if q.IsRange() {
+		q.Znak = "between ? and ?"
+		return q, nil
+	}
+
+	q.Znak += " ?"
+
+	return q, nil
+}
+
+func (s *SQLServerFormat) Query(q *QueryParams, columns []string) (query string, values []any) {
+	var fields []string
+	// Если нет параметров, то выходим
+	if q.Len() == 0 && q.ID == nil {
+		return "", nil
+	}
+
+	// Формирование полей для поиска везде OR
+	if vals, ok := q.m[AllFieldsParamName]; ok && len(vals) > 0 {
+		value := vals[0]
+		if q.Filter != nil && len(q.Filter.Fields) > 0 {
+			columns = q.Filter.Fields
+		}
+		for _, column := range columns {
+			if _, ok = q.m[column]; !ok {
+				key := s.quote(column)
+				if value.IsQuotes {
+					key = "CAST(" + key + " AS NVARCHAR(MAX))"
+				}
+				fields = append(fields, s.condition(key, value.Znak))
+				values = append(values, value.Value)
+			}
+		}
+	}
+	if len(fields) > 0 {
+		query = "(" + strings.Join(fields, " or ") + ")"
+	}
+	// Заполняем строку запроса и значения для неё по дереву условий
+	v, args := q.Where(func(param *QueryParam) string {
+		key := param.Key
+		if param.IsQuotes {
+			key = s.quote(key)
+		}
+		return s.condition(key, param.Znak)
+	})
+	values = append(values, args...)
+	if len(v) > 0 {
+		if len(query) > 0 {
+			query += " and " + v
+		} else {
+			query = v
+		}
+	}
+
+	// Избавляемся от nil значений
+	var vals []any
+	for _, value := range values {
+		if value != nil {
+			vals = append(vals, value)
+		}
+	}
+
+	return query, vals
+}
+
+// DefaultPlaceholder Плейсхолдеры @p1..@pN
+func (s *SQLServerFormat) DefaultPlaceholder() Placeholder {
+	return PlaceholderAtP
+}
+
+// Paging Сортировка и постраничный вывод ORDER BY ... OFFSET ... ROWS FETCH NEXT ... ROWS ONLY
+// NULLS FIRST/LAST в T-SQL нет, поэтому положение null задаётся дополнительной сортировкой
+func (s *SQLServerFormat) Paging(f *Filter, columns []string) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	orders, err := ParseOrders(f.Orders, columns)
+	if err != nil {
+		return "", err
+	}
+	var query string
+	if len(orders) > 0 {
+		var a []string
+		for _, o := range orders {
+			key := s.quote(o.Field)
+			switch o.Nulls {
+			case "FIRST":
+				a = append(a, "CASE WHEN "+key+" IS NULL THEN 0 ELSE 1 END")
+			case "LAST":
+				a = append(a, "CASE WHEN "+key+" IS NULL THEN 1 ELSE 0 END")
+			}
+			a = append(a, key+" "+o.Direction())
+		}
+		query = "ORDER BY " + strings.Join(a, ", ")
+	}
+	if f.Limit <= 0 && f.Offset <= 0 {
+		return query, nil
+	}
+	// OFFSET без ORDER BY в T-SQL недопустим
+	if len(query) == 0 {
+		query = "ORDER BY (SELECT NULL)"
+	}
+	offset := 0
+	if f.Offset > 0 {
+		offset = f.Offset
+	}
+	query += " OFFSET " + strconv.Itoa(offset) + " ROWS"
+	if f.Limit > 0 {
+		query += " FETCH NEXT " + strconv.Itoa(f.Limit) + " ROWS ONLY"
+	}
+	return query, nil
+}
+
+// Seek Условие постраничного вывода по курсору. Сравнения кортежей в T-SQL нет, поэтому (a > ?) or (a = ? and b > ?)
+func (s *SQLServerFormat) Seek(f *Filter) (string, []any) {
+	return seekCondition(f, s.quote, false)
+}
+
+// condition Подстановка поля в выражение оператора
+func (s *SQLServerFormat) condition(key, znak string) string {
+	if strings.Contains(znak, keyMark) {
+		return strings.ReplaceAll(znak, keyMark, key)
+	}
+	return strings.Trim(key+" "+znak, " ")
+}
+
+// quote Экранирование идентификатора квадратными скобками, в том числе schema.table
+func (s *SQLServerFormat) quote(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "[" + strings.ReplaceAll(part, "]", "]]") + "]"
+	}
+	return strings.Join(parts, ".")
+}
+
+// isJSONContainer Проверка, что значение - объект или массив json
+func isJSONContainer(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")
+}
@@ -0,0 +1,77 @@
+package crud
+
+import (
+	"testing"
+)
+
+func getSQLServerCRUD() *CRUD {
+	return New(new(functions)).SetIQueryParam(new(SQLServerFormat))
+}
+
+func TestSQLServerParams(t *testing.T) {
+	r := getSQLServerCRUD()
+	q := &QueryParams{}
+	q.ID = QueryFormat(r, "id", "1::int")
+	q.Set("name", QueryFormat(r, "name", "[a,b,c]"))
+	query, values := r.Query(q, r.Columns(r))
+	assertEq(t, query, `[id] = @p1 and [name] in(@p2,@p3,@p4)`)
+	assertArrayEq(t, []any{1, "a", "b", "c"}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "2::int")
+	q.Set("*", QueryFormat(r, "*[%]", "Зна%"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, `(CAST([id] AS NVARCHAR(MAX)) LIKE @p1 or CAST([name] AS NVARCHAR(MAX)) LIKE @p2) and [id] = @p3`)
+	assertArrayEq(t, []any{"Зна%", "Зна%", 2}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id[:]", "[1|10]::int")
+	q.Set("name", QueryFormat(r, "name[!]", "null"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, `[id] between @p1 and @p2 and [name] is not null`)
+	assertArrayEq(t, []any{1, 10}, values)
+
+	q = &QueryParams{}
+	q.ID = QueryFormat(r, "id", "3::int")
+	q.Set("result", QueryFormat(r, "result[->>]", "type[!%]=2%"))
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, `[id] = @p1 and JSON_VALUE([result], '$.type') NOT LIKE @p2`)
+
+	// Скалярное значение по пути сравнивается через JSON_VALUE, объект - через JSON_QUERY
+	q = &QueryParams{}
+	q.Set("result", QueryFormat(r, "result[->]", "type=2"))
+	query, values = r.Query(q, r.Columns(r))
+	assertEq(t, query, `JSON_VALUE([result], '$.type') = @p1`)
+	assertArrayEq(t, []any{"2"}, values)
+
+	q = &QueryParams{}
+	q.Set("result", QueryFormat(r, "result[->]", `meta={"a":1}`))
+	query, _ = r.Query(q, r.Columns(r))
+	assertEq(t, query, `JSON_QUERY([result], '$.meta') = @p1`)
+
+	if _, err := r.QueryFormat("tags[&&]", "[a,b]"); err == nil {
+		t.Fatal("expected error for [&&]")
+	}
+}
+
+func TestSQLServerPaging(t *testing.T) {
+	s := new(SQLServerFormat)
+	paging, err := s.Paging(&Filter{Orders: []string{"name desc nulls last", "id"}, Limit: 10, Offset: 20}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, paging, `ORDER BY CASE WHEN [name] IS NULL THEN 1 ELSE 0 END, [name] DESC, [id] ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`)
+
+	paging, err = s.Paging(&Filter{Limit: 5, Offset: -1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, paging, `ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY`)
+
+	if _, err = s.Paging(&Filter{Orders: []string{"name; drop table users"}}, nil); err == nil {
+		t.Fatal("expected order error")
+	}
+	if _, err = s.Paging(&Filter{Orders: []string{"age"}}, []string{"id", "name"}); err == nil {
+		t.Fatal("expected unknown order field error")
+	}
+}
@@ -0,0 +1,167 @@
+package crud
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// RecordsFunc Источник записей: вызывает yield для каждой записи и возвращает статус.
+// ctx отменяется при отключении клиента или ошибке отправки ответа
+type RecordsFunc func(ctx context.Context, yield func(record Map) error) (int, error)
+
+// RecordsOf Источник записей из уже полученного среза
+func RecordsOf(status int, records Maps) RecordsFunc {
+	return func(ctx context.Context, yield func(record Map) error) (int, error) {
+		for _, record := range records {
+			if err := ctx.Err(); err != nil {
+				return status, err
+			}
+			if err := yield(record); err != nil {
+				return status, err
+			}
+		}
+		return status, nil
+	}
+}
+
+// recordWriter Построчная запись в поток ответа
+type recordWriter interface {
+	Write(record Map) error
+	Close() error
+}
+
+type streamResult struct {
+	status int
+	err    error
+}
+
+// SendNDJSON Потоковая отдача записей в application/x-ndjson, по одной записи json в строке
+func (r *CRUD) SendNDJSON(c *ewa.Context, records RecordsFunc) error {
+	return r.sendStream(c, MIMEApplicationNDJSON, nil, records, func(w io.Writer) recordWriter {
+		return &ndjsonWriter{json.NewEncoder(w)}
+	})
+}
+
+// sendStream Потоковая отдача записей. Записи пишутся в горутине по мере поступления,
+// ответ с заголовками headers начинается после первых байт в потоке, поэтому ошибка до них возвращается обычным ответом.
+// Горутина не обращается к c: сервер может читать поток после возврата из обработчика маршрута.
+// При отключении клиента или ошибке SendStream поток закрывается, а контекст записей отменяется
+func (r *CRUD) sendStream(c *ewa.Context, contentType string, headers map[string]string, records RecordsFunc, writer func(w io.Writer) recordWriter) error {
+	ctx, cancel := context.WithCancel(contextOf(c))
+	pr, pw := io.Pipe()
+	ready := make(chan streamResult, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		sw := &startWriter{w: pw, ready: ready}
+		w := writer(sw)
+		status, err := records(ctx, func(record Map) error {
+			record.Excludes(r.Excludes...)
+			return w.Write(record)
+		})
+		if err == nil {
+			err = w.Close()
+		}
+		if !sw.started {
+			ready <- streamResult{status: status, err: err}
+		}
+		pw.CloseWithError(err)
+	}()
+
+	go func() {
+		defer cancel()
+		select {
+		case <-ctx.Done():
+			pr.CloseWithError(ctx.Err())
+		case <-finished:
+		}
+	}()
+
+	result := <-ready
+	if result.err != nil {
+		pr.Close()
+		return r.Send(c, Read, result.status, result.err)
+	}
+	for key, value := range headers {
+		c.Set(key, value)
+	}
+	if err := c.SendStream(result.status, contentType, pr); err != nil {
+		cancel()
+		return err
+	}
+	return nil
+}
+
+// startWriter Запись в поток ответа, которая начинает ответ со статусом 200 перед первыми байтами
+type startWriter struct {
+	w       io.Writer
+	ready   chan<- streamResult
+	started bool
+}
+
+func (w *startWriter) Write(p []byte) (int, error) {
+	if !w.started && len(p) > 0 {
+		w.started = true
+		w.ready <- streamResult{status: consts.StatusOK}
+	}
+	return w.w.Write(p)
+}
+
+// ndjsonWriter Запись в формате NDJSON
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(record Map) error {
+	return w.enc.Encode(record)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// csvWriter Запись в формате CSV. Заголовок пишется перед первой строкой,
+// без заранее известных полей они берутся из ключей первой записи
+type csvWriter struct {
+	w      *csv.Writer
+	fields []string
+	header bool
+}
+
+func (w *csvWriter) writeHeader(record Map) error {
+	w.header = true
+	if len(w.fields) == 0 && record != nil {
+		w.fields = sortedKeys(record)
+	}
+	return w.w.Write(w.fields)
+}
+
+func (w *csvWriter) Write(record Map) error {
+	if !w.header {
+		if err := w.writeHeader(record); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(w.fields))
+	for i, field := range w.fields {
+		row[i] = csvValue(record[field])
+	}
+	return w.w.Write(row)
+}
+
+func (w *csvWriter) Close() error {
+	if !w.header {
+		if err := w.writeHeader(nil); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
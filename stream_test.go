@@ -0,0 +1,167 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+// streamHandlers Обработчики с построчной выдачей записей
+type streamHandlers struct {
+	Handlers
+	err error
+}
+
+func (h *streamHandlers) StreamRecords(ctx context.Context, r *CRUD, params *QueryParams, yield func(record Map) error) (int, error) {
+	if h.err != nil {
+		return consts.StatusBadRequest, h.err
+	}
+	for i := 1; i <= 3; i++ {
+		if err := yield(Map{"id": i, "name": fmt.Sprintf("Name%d", i)}); err != nil {
+			return consts.StatusInternalServerError, err
+		}
+	}
+	return consts.StatusOK, nil
+}
+
+func TestReadHandler_NDJSON(t *testing.T) {
+	tc := newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON)
+	ctx := &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.contentType, MIMEApplicationNDJSON)
+	assertEq(t, string(tc.response.body), "{\"id\":1,\"name\":\"Name\"}\n{\"id\":2,\"name\":\"Name2\"}\n")
+
+	// Построчная выдача через IStreamHandlers
+	tc = newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON)
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(new(streamHandlers)).SetModelName("table").SetFieldIdName("id").SetExcludes("name").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, string(tc.response.body), "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+	_, ok := tc.response.headers[HeaderTotal]
+	assertEq(t, ok, false)
+
+	tc = newTestContext().SetHeader(consts.HeaderAccept, "text/csv")
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(new(streamHandlers)).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, string(tc.response.body), "id,name\n1,Name1\n2,Name2\n3,Name3\n")
+
+	// Записи по курсору назад разворачиваются, столбец курсора вне fields исключается
+	prev := (&Cursor{Values: []any{9}, Prev: true}).Encode()
+	tc = newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON).AddQuery("~", `{"fields":["name"],"limit":3,"cursor":"`+prev+`"}`)
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(new(streamHandlers)).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, string(tc.response.body), "{\"name\":\"Name3\"}\n{\"name\":\"Name2\"}\n{\"name\":\"Name1\"}\n")
+
+	// Ошибка до первой записи возвращается обычным ответом
+	tc = newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON)
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(&streamHandlers{err: fmt.Errorf("bad query")}).SetModelName("table").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemJSON)
+	assertEq(t, strings.Contains(string(tc.response.body), `"detail":"bad query"`), true)
+}
+
+// failedStreamHandlers Обработчики, построчная выдача которых прерывается ошибкой после записи record
+type failedStreamHandlers struct {
+	Handlers
+	record Map
+}
+
+func (h *failedStreamHandlers) StreamRecords(ctx context.Context, r *CRUD, params *QueryParams, yield func(record Map) error) (int, error) {
+	if err := yield(h.record); err != nil {
+		return consts.StatusInternalServerError, err
+	}
+	return consts.StatusInternalServerError, errors.New("connection lost")
+}
+
+func TestReadHandler_StreamFailedBeforeBytes(t *testing.T) {
+	// Строка CSV ещё в буфере, поэтому ошибка возвращается обычным ответом, а не 200 с обрезанным телом
+	tc := newTestContext().SetHeader(consts.HeaderAccept, "text/csv")
+	h := &failedStreamHandlers{record: Map{"id": 1}}
+	if err := New(h).SetModelName("table").ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 500)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemJSON)
+	assertEq(t, strings.Contains(string(tc.response.body), `"detail":"connection lost"`), true)
+
+	// Первая запись не кодируется
+	tc = newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON)
+	h = &failedStreamHandlers{record: Map{"id": make(chan int)}}
+	if err := New(h).SetModelName("table").ReadHandler(&ewa.Context{IContext: tc}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 500)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemJSON)
+}
+
+func TestSQLHandlers_StreamRecords(t *testing.T) {
+	d, r := newFakeSQL()
+	var records Maps
+	status, err := r.IHandlers.(IStreamHandlers).StreamRecords(context.Background(), r, &QueryParams{}, func(record Map) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, status, 200)
+	assertEq(t, len(records), 2)
+	assertEq(t, records[1]["name"], "Name2")
+	assertEq(t, len(d.queries), 1)
+	assertEq(t, d.queries[0], `SELECT * FROM "public"."users"`)
+}
+
+// endlessHandlers Обработчики с бесконечной построчной выдачей, done получает ошибку остановки
+type endlessHandlers struct {
+	Handlers
+	done chan error
+}
+
+func (h *endlessHandlers) StreamRecords(ctx context.Context, r *CRUD, params *QueryParams, yield func(record Map) error) (int, error) {
+	for i := 0; ; i++ {
+		if err := yield(Map{"id": i}); err != nil {
+			h.done <- ctx.Err()
+			return consts.StatusInternalServerError, err
+		}
+	}
+}
+
+// failedSendContext Контекст, который не читает поток ответа
+type failedSendContext struct {
+	*testContext
+}
+
+func (c failedSendContext) SendStream(int, string, io.Reader) error {
+	return errors.New("connection reset")
+}
+
+func TestReadHandler_StreamStopped(t *testing.T) {
+	h := &endlessHandlers{done: make(chan error, 1)}
+	tc := newTestContext().SetHeader(consts.HeaderAccept, MIMEApplicationNDJSON)
+	err := New(h).SetModelName("table").ReadHandler(&ewa.Context{IContext: failedSendContext{tc}}, nil, nil)
+	assertEq(t, err != nil, true)
+	select {
+	case err = <-h.done:
+		assertEq(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("stream producer is not stopped")
+	}
+}
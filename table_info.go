@@ -0,0 +1,110 @@
+package crud
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// ColumnInfo Описание столбца таблицы для заголовка Table-Info
+type ColumnInfo struct {
+	Name        string   `json:"name" xml:"name,attr"`
+	Type        string   `json:"type,omitempty" xml:"type,omitempty"`
+	Nullable    bool     `json:"nullable" xml:"nullable"`
+	Default     *string  `json:"default,omitempty" xml:"default,omitempty"`
+	Description string   `json:"description,omitempty" xml:"description,omitempty"`
+	PrimaryKey  bool     `json:"primary_key" xml:"primary_key"`
+	Filterable  bool     `json:"filterable" xml:"filterable"`
+	Sortable    bool     `json:"sortable" xml:"sortable"`
+	Operators   []string `json:"operators,omitempty" xml:"operator,omitempty"`
+}
+
+// TableInfo Описание таблицы и её столбцов
+type TableInfo struct {
+	XMLName xml.Name     `json:"-" xml:"table"`
+	Name    string       `json:"name" xml:"name,attr"`
+	Columns []ColumnInfo `json:"columns" xml:"column"`
+}
+
+// ITableInfoHandlers Необязательное расширение IHandlers с описанием столбцов.
+// Если поля не переданы, то возвращаются все столбцы. Пустые Operators заполняются по шаблону диалекта
+type ITableInfoHandlers interface {
+	ColumnsInfo(r *CRUD, fields ...string) ([]ColumnInfo, error)
+}
+
+// TableInfo Описание столбцов таблицы без исключённых полей.
+// Без ITableInfoHandlers известны только имя, первичный ключ и операторы
+func (r *CRUD) TableInfo(fields ...string) (*TableInfo, error) {
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	var columns []ColumnInfo
+	if h, ok := r.IHandlers.(ITableInfoHandlers); ok {
+		var err error
+		if columns, err = h.ColumnsInfo(r, fields...); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, name := range r.Columns(r, fields...) {
+			columns = append(columns, ColumnInfo{
+				Name:       name,
+				PrimaryKey: name == r.FieldIdName,
+				Filterable: true,
+				Sortable:   true,
+			})
+		}
+	}
+	info := &TableInfo{Name: r.ModelName, Columns: make([]ColumnInfo, 0, len(columns))}
+	ops := operators(r.Pattern())
+	for _, column := range columns {
+		if isExcluded(column.Name, r.Excludes) {
+			continue
+		}
+		if column.Filterable && column.Operators == nil {
+			column.Operators = columnOperators(column.Type, ops)
+		}
+		info.Columns = append(info.Columns, column)
+	}
+	return info, nil
+}
+
+// operators Операторы условий из шаблона диалекта IQueryParam.Pattern
+func operators(pattern string) []string {
+	start, end := strings.Index(pattern, "("), strings.LastIndex(pattern, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+	var result []string
+	for _, op := range strings.Split(pattern[start+1:end], "|") {
+		// Пропуск шаблонов вроде [aA-zZ]+
+		if strings.Contains(op, "[") {
+			continue
+		}
+		result = append(result, strings.ReplaceAll(op, `\`, ""))
+	}
+	return result
+}
+
+// columnOperators Операторы, применимые к типу столбца. Если тип не известен, то все
+func columnOperators(columnType string, ops []string) []string {
+	if len(columnType) == 0 {
+		return ops
+	}
+	t := strings.ToLower(columnType)
+	isJSON := strings.Contains(t, "json")
+	isArray := t == "array" || strings.HasSuffix(t, "[]")
+	result := make([]string, 0, len(ops))
+	for _, op := range ops {
+		switch op {
+		case "->", "->>":
+			if !isJSON {
+				continue
+			}
+		case "array", "&&", "!array", "!&&":
+			if !isArray {
+				continue
+			}
+		}
+		result = append(result, op)
+	}
+	return result
+}
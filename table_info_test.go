@@ -0,0 +1,98 @@
+package crud
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+func TestOperators(t *testing.T) {
+	ops := operators(new(PostgresFormat).Pattern())
+	assertArrayStringEq(t, ops, []string{"->", "->>", ">", "<", ">-", "<-", "!", "<>", "array", "&&", "!array", "!&&", "~", "!~", "~*", "!~*", "+", "!+", "%", ":"})
+	assertArrayStringEq(t, columnOperators("integer", []string{"->", ">", "&&"}), []string{">"})
+	assertArrayStringEq(t, columnOperators("jsonb", []string{"->", ">", "&&"}), []string{"->", ">"})
+	assertArrayStringEq(t, columnOperators("text[]", []string{"->", ">", "&&"}), []string{">", "&&"})
+}
+
+func TestTableInfo(t *testing.T) {
+	r := getCRUD().SetModelName("users").SetFieldIdName("id").SetExcludes("name")
+	info, err := r.TableInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, info.Name, "users")
+	assertEq(t, len(info.Columns), 1)
+	assertEq(t, info.Columns[0].Name, "id")
+	assertEq(t, info.Columns[0].PrimaryKey, true)
+	assertEq(t, len(info.Columns[0].Operators), 20)
+}
+
+func TestSQLHandlers_ColumnsInfo(t *testing.T) {
+	d, r := newFakeSQL()
+	info, err := r.TableInfo("id", " data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, strings.HasSuffix(d.queries[0], `WHERE c.table_name = $1 AND c.table_schema = $2 ORDER BY c.ordinal_position`), true)
+	assertArrayStringEq(t, d.args[0], []any{"users", "public"})
+	assertEq(t, len(info.Columns), 2)
+
+	id := info.Columns[0]
+	assertEq(t, id.Type, "integer")
+	assertEq(t, id.Nullable, false)
+	assertEq(t, *id.Default, "nextval('users_id_seq'::regclass)")
+	assertEq(t, id.PrimaryKey, true)
+	assertEq(t, id.Sortable, true)
+	assertEq(t, strings.Contains(strings.Join(id.Operators, " "), "->"), false)
+
+	data := info.Columns[1]
+	assertEq(t, data.Nullable, true)
+	assertEq(t, data.Default == nil, true)
+	assertEq(t, data.Sortable, false)
+	assertEq(t, data.Operators[0], "->")
+
+	info, err = r.TableInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, strings.Contains(d.queries[1], "col_description("), true)
+	assertEq(t, info.Columns[0].Description, "")
+	assertEq(t, info.Columns[1].Description, "User name")
+}
+
+func TestReadHandler_TableInfo(t *testing.T) {
+	tc := newTestContext().SetHeader(HeaderTableInfo, "full")
+	ctx := &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("users").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	var info TableInfo
+	if err := json.Unmarshal(tc.response.body, &info); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, len(info.Columns), 2)
+	assertEq(t, info.Columns[1].Name, "name")
+
+	tc = newTestContext().SetHeader(HeaderTableInfo, "full").SetHeader(consts.HeaderAccept, consts.MIMEApplicationXML)
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("users").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, strings.HasPrefix(string(tc.response.body), `<table name="users"><column name="id"><nullable>false</nullable><primary_key>true</primary_key>`), true)
+
+	// Список полей возвращает только имена столбцов
+	tc = newTestContext().SetHeader(HeaderTableInfo, "id")
+	ctx = &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("users").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	var columns []string
+	if err := json.Unmarshal(tc.response.body, &columns); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, columns[0], "id")
+}
@@ -0,0 +1,192 @@
+package crud
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+// ITypedHandlers Типизированные обработчики записей структуры T.
+// UpdateRecord получает также имена столбцов, переданных в теле запроса, чтобы отличить их от нулевых значений
+type ITypedHandlers[T any] interface {
+	GetRecord(c *ewa.Context, r *CRUD, params *QueryParams) (status int, data *T, err error)
+	GetRecords(c *ewa.Context, r *CRUD, params *QueryParams) (status int, data []T, total int64, err error)
+	SetRecord(c *ewa.Context, r *CRUD, data *T, params *QueryParams) (status int, result any, err error)
+	UpdateRecord(c *ewa.Context, r *CRUD, data *T, columns []string, params *QueryParams) (status int, result any, err error)
+	DeleteRecord(c *ewa.Context, r *CRUD, params *QueryParams) (status int, result any, err error)
+}
+
+// Typed Реализация IHandlers поверх типизированных обработчиков.
+// Имена столбцов берутся из тегов crud:"name", иначе json:"name", иначе из имени поля
+type Typed[T any] struct {
+	Handlers ITypedHandlers[T]
+	functions
+}
+
+// NewTyped Инициализация CRUD с типизированными обработчиками
+func NewTyped[T any](h ITypedHandlers[T]) *CRUD {
+	return New(&Typed[T]{Handlers: h})
+}
+
+// Columns Столбцы из тегов структуры T
+func (t *Typed[T]) Columns(r *CRUD, fields ...string) []string {
+	var columns []string
+	for _, column := range columnsOf(reflect.TypeOf((*T)(nil)).Elem()) {
+		if len(fields) == 0 || isExcluded(column.name, fields) {
+			columns = append(columns, column.name)
+		}
+	}
+	return columns
+}
+
+func (t *Typed[T]) GetRecord(c *ewa.Context, r *CRUD, params *QueryParams) (int, Map, error) {
+	status, data, err := t.Handlers.GetRecord(c, r, params)
+	if err != nil || data == nil {
+		return status, nil, err
+	}
+	return status, ToMap(data), nil
+}
+
+func (t *Typed[T]) GetRecords(c *ewa.Context, r *CRUD, params *QueryParams) (int, Maps, int64, error) {
+	status, data, total, err := t.Handlers.GetRecords(c, r, params)
+	if err != nil {
+		return status, nil, 0, err
+	}
+	records := make(Maps, len(data))
+	for i := range data {
+		records[i] = ToMap(&data[i])
+	}
+	return status, records, total, nil
+}
+
+func (t *Typed[T]) SetRecord(c *ewa.Context, r *CRUD, data *Body, params *QueryParams) (int, any, error) {
+	return eachRecord[T](data, func(v *T, columns []string) (int, any, error) {
+		return t.Handlers.SetRecord(c, r, v, params)
+	})
+}
+
+func (t *Typed[T]) UpdateRecord(c *ewa.Context, r *CRUD, data *Body, params *QueryParams) (int, any, error) {
+	return eachRecord[T](data, func(v *T, columns []string) (int, any, error) {
+		return t.Handlers.UpdateRecord(c, r, v, columns, params)
+	})
+}
+
+func (t *Typed[T]) DeleteRecord(c *ewa.Context, r *CRUD, params *QueryParams) (int, any, error) {
+	return t.Handlers.DeleteRecord(c, r, params)
+}
+
+// eachRecord Вызов fn для каждой записи тела запроса. Для массива результаты собираются в срез
+func eachRecord[T any](data *Body, fn func(v *T, columns []string) (int, any, error)) (int, any, error) {
+	if !data.IsArray {
+		m := data.ToMap()
+		v, err := FromMap[T](m)
+		if err != nil {
+			return consts.StatusBadRequest, nil, ErrValidation.Wrap(err)
+		}
+		return fn(v, sortedKeys(m))
+	}
+	status, results := consts.StatusOK, make([]any, 0, len(data.Array))
+	for i := range data.Array {
+		m := data.ToArrayMap(i)
+		v, err := FromMap[T](m)
+		if err != nil {
+			return consts.StatusBadRequest, nil, ErrValidation.Wrap(err)
+		}
+		var result any
+		if status, result, err = fn(v, sortedKeys(m)); err != nil {
+			return status, nil, err
+		}
+		results = append(results, result)
+	}
+	return status, results, nil
+}
+
+// column Столбец структуры: имя в бд, имя в json и индекс поля
+type column struct {
+	name     string
+	jsonName string
+	index    []int
+}
+
+var typeColumns sync.Map
+
+// columnsOf Столбцы структуры с учётом встроенных структур
+func columnsOf(t reflect.Type) []column {
+	if cached, ok := typeColumns.Load(t); ok {
+		return cached.([]column)
+	}
+	var columns []column
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous && isStruct(f.Type) {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if len(jsonName) == 0 {
+			jsonName = f.Name
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("crud"), ",")
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = jsonName
+		}
+		columns = append(columns, column{name: name, jsonName: jsonName, index: f.Index})
+	}
+	typeColumns.Store(t, columns)
+	return columns
+}
+
+// isStruct Структура или указатель на структуру
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// ToMap Запись из структуры по тегам crud и json
+func ToMap[T any](v *T) Map {
+	rv := reflect.ValueOf(v).Elem()
+	columns := columnsOf(rv.Type())
+	m := make(Map, len(columns))
+	for _, column := range columns {
+		f, err := rv.FieldByIndexErr(column.index)
+		if err != nil {
+			continue
+		}
+		m[column.name] = f.Interface()
+	}
+	return m
+}
+
+// FromMap Структура из записи по тегам crud и json. Значения приводятся к типам полей через json
+func FromMap[T any](m map[string]interface{}) (*T, error) {
+	columns := columnsOf(reflect.TypeOf((*T)(nil)).Elem())
+	data := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		for _, column := range columns {
+			if column.name == key {
+				key = column.jsonName
+				break
+			}
+		}
+		data[key] = value
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err = json.Unmarshal(b, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
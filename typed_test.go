@@ -0,0 +1,146 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ewa-go/ewa"
+	"github.com/ewa-go/ewa/consts"
+)
+
+type typedBase struct {
+	ID int `json:"id"`
+}
+
+type typedUser struct {
+	typedBase
+	Name     string `json:"name"`
+	Email    string `json:"email" crud:"mail"`
+	Password string `json:"-"`
+	internal int
+}
+
+// typedHandlers Типизированные обработчики для тестов
+type typedHandlers struct {
+	created *typedUser
+	columns []string
+}
+
+func (h *typedHandlers) GetRecord(c *ewa.Context, r *CRUD, params *QueryParams) (int, *typedUser, error) {
+	return consts.StatusOK, &typedUser{typedBase: typedBase{ID: 1}, Name: "Name", Email: "name@example.com"}, nil
+}
+
+func (h *typedHandlers) GetRecords(c *ewa.Context, r *CRUD, params *QueryParams) (int, []typedUser, int64, error) {
+	return consts.StatusOK, []typedUser{
+		{typedBase: typedBase{ID: 1}, Name: "Name", Email: "name@example.com", Password: "secret"},
+		{typedBase: typedBase{ID: 2}, Name: "Name2", Email: "name2@example.com"},
+	}, 2, nil
+}
+
+func (h *typedHandlers) SetRecord(c *ewa.Context, r *CRUD, data *typedUser, params *QueryParams) (int, any, error) {
+	h.created = data
+	return consts.StatusCreated, data.ID, nil
+}
+
+func (h *typedHandlers) UpdateRecord(c *ewa.Context, r *CRUD, data *typedUser, columns []string, params *QueryParams) (int, any, error) {
+	h.created, h.columns = data, columns
+	return consts.StatusOK, nil, nil
+}
+
+func (h *typedHandlers) DeleteRecord(c *ewa.Context, r *CRUD, params *QueryParams) (int, any, error) {
+	return consts.StatusOK, nil, nil
+}
+
+func TestTyped_Columns(t *testing.T) {
+	r := NewTyped[typedUser](new(typedHandlers))
+	assertArrayStringEq(t, r.Columns(r), []string{"id", "name", "mail"})
+	assertArrayStringEq(t, r.Columns(r, "mail", "age"), []string{"mail"})
+
+	m := ToMap(&typedUser{typedBase: typedBase{ID: 1}, Name: "Name", Email: "name@example.com"})
+	assertEq(t, len(m), 3)
+	assertEq(t, m["mail"], "name@example.com")
+
+	u, err := FromMap[typedUser](map[string]interface{}{"id": 2, "mail": "a@b.c", "name": "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, u.ID, 2)
+	assertEq(t, u.Email, "a@b.c")
+
+	if _, err = FromMap[typedUser](map[string]interface{}{"id": "x"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	type pointerUser struct {
+		*typedBase
+		Name string `json:"name"`
+	}
+	assertEq(t, len(columnsOf(reflect.TypeOf(pointerUser{}))), 2)
+	m = ToMap(&pointerUser{Name: "Name"})
+	assertEq(t, len(m), 1)
+}
+
+func TestTyped_ReadHandler(t *testing.T) {
+	tc := newTestContext()
+	ctx := &ewa.Context{IContext: tc}
+	if err := NewTyped[typedUser](new(typedHandlers)).SetModelName("users").SetFieldIdName("id").SetExcludes("mail").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, string(tc.response.body), `[{"id":1,"name":"Name"},{"id":2,"name":"Name2"}]`)
+
+	tc = newTestContext().SetHeader(consts.HeaderAccept, "text/csv")
+	ctx = &ewa.Context{IContext: tc}
+	if err := NewTyped[typedUser](new(typedHandlers)).SetModelName("users").SetFieldIdName("id").ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, string(tc.response.body), "id,name,mail\n1,Name,name@example.com\n2,Name2,name2@example.com\n")
+}
+
+func TestTyped_CreateHandler(t *testing.T) {
+	h := new(typedHandlers)
+	tc := newTestContext().
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"id":3,"name":"Name3","mail":"name3@example.com"}`)
+	ctx := &ewa.Context{IContext: tc}
+	r := NewTyped[typedUser](h).SetModelName("users").SetFieldIdName("id").SetStrict(true)
+	if err := r.CreateHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 201)
+	assertEq(t, h.created.ID, 3)
+	assertEq(t, h.created.Email, "name3@example.com")
+
+	// Столбец не из структуры отклоняется в строгом режиме
+	tc = newTestContext().
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"email":"name3@example.com"}`)
+	ctx = &ewa.Context{IContext: tc}
+	if err := r.CreateHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+
+	// Значение другого типа
+	tc = newTestContext().
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetBody(`{"id":"x"}`)
+	ctx = &ewa.Context{IContext: tc}
+	if err := r.CreateHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+	assertEq(t, tc.response.contentType, MIMEApplicationProblemJSON)
+
+	tc = newTestContext().
+		SetHeader(consts.HeaderContentType, consts.MIMEApplicationJSON).
+		SetParam("id", "3").
+		SetBody(`{"name":"Name4"}`)
+	ctx = &ewa.Context{IContext: tc}
+	if err := r.UpdateHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 200)
+	assertEq(t, h.created.Name, "Name4")
+	assertArrayStringEq(t, h.columns, []string{"name"})
+}
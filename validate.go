@@ -0,0 +1,61 @@
+package crud
+
+import (
+	"fmt"
+)
+
+// SetStrict Строгий режим: ключи query параметров, поля фильтра и тела запроса проверяются по столбцам таблицы
+func (r *CRUD) SetStrict(strict bool) *CRUD {
+	r.Strict = strict
+	return r
+}
+
+// Validate Проверка идентификаторов запроса по столбцам IHandlers.Columns в строгом режиме.
+// Если столбцы не известны, то допускаются только простые идентификаторы
+func (r *CRUD) Validate(q *QueryParams, body *Body) error {
+	if !r.Strict {
+		return nil
+	}
+	columns := r.Columns(r)
+	if q != nil {
+		if q.ID != nil && !isColumn(q.ID.Key, columns) {
+			return unknownField(q.ID.Key, "query")
+		}
+		var err error
+		q.Tree().Walk(func(e *Expr) bool {
+			if err == nil && !e.IsGroup() && !isColumn(e.Param.Key, columns) {
+				err = unknownField(e.Param.Key, "query")
+			}
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+		if q.Filter != nil {
+			for _, field := range q.Filter.Fields {
+				if !isColumn(field, columns) {
+					return unknownField(field, "filter")
+				}
+			}
+		}
+	}
+	if body != nil {
+		data := []map[string]interface{}{body.Data}
+		if body.IsArray {
+			data = body.Array
+		}
+		for _, m := range data {
+			for _, key := range sortedKeys(m) {
+				if !isColumn(key, columns) {
+					return unknownField(key, "body")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// unknownField Ошибка проверки неизвестного поля
+func unknownField(field, place string) error {
+	return Validation(fmt.Sprintf("unknown field %s in %s", field, place), FieldError{Field: field, Message: "unknown field"})
+}
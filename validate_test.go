@@ -0,0 +1,59 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/ewa-go/ewa"
+)
+
+func TestValidate(t *testing.T) {
+	r := getCRUD()
+
+	q := &QueryParams{Filter: &Filter{Fields: []string{"id", "password"}}}
+	q.Set("name", QueryFormat(r, "name", "Name"))
+	// Без строгого режима поля не проверяются
+	if err := r.Validate(q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r.SetStrict(true)
+	if err := r.Validate(q, nil); err == nil || err.Error() != "unknown field password in filter" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	q = &QueryParams{}
+	q.Set(`name"; drop table users; --`, QueryFormat(r, `name"; drop table users; --`, "1"))
+	if err := r.Validate(q, nil); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+
+	q = &QueryParams{}
+	q.Set("name", QueryFormat(r, "[g]name[!array]", "[a,b]"))
+	body := NewBody("id")
+	body.Data = map[string]interface{}{"name": "Name", "age": 1}
+	if err := r.Validate(q, body); err == nil || err.Error() != "unknown field age in body" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	body.SetIsArray(true)
+	body.Array = []map[string]interface{}{{"id": 1, "name": "Name"}}
+	if err := r.Validate(q, body); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	r := getCRUD()
+	q := &QueryParams{}
+	q.Set(`na"me`, QueryFormat(r, `na"me`, "1"))
+	query, _ := r.Query(q, nil)
+	assertEq(t, query, `"na""me" = ?`)
+}
+
+func TestReadHandler_Strict(t *testing.T) {
+	tc := newTestContext().AddQuery("age", "1")
+	ctx := &ewa.Context{IContext: tc}
+	if err := New(h).SetModelName("table").SetFieldIdName("id").SetStrict(true).ReadHandler(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertEq(t, tc.response.status, 400)
+}